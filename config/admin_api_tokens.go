@@ -0,0 +1,278 @@
+package config
+
+import (
+	"claude2api/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAdminAPITokensFile = "data/admin_api_tokens.json"
+	// AdminAPITokenPrefix 管理API token的明文前缀，用于与 JWT 和业务 Key 区分
+	AdminAPITokenPrefix = "c2t-"
+	// 最近使用时间写回文件的最小间隔
+	adminAPITokenTouchInterval = time.Minute
+)
+
+// 管理API token的权限范围
+const (
+	AdminScopeSessionsRead   = "sessions:read"   // 查看sessions与统计，导出脱敏的sessions
+	AdminScopeSessionsWrite  = "sessions:write"  // 添加、修改、删除、导入sessions及切换状态
+	AdminScopeSessionsExport = "sessions:export" // 导出未脱敏的sessionKey
+	AdminScopeConfigRead     = "config:read"
+	AdminScopeConfigWrite    = "config:write" // 修改与热加载配置
+	AdminScopeAPIKeysRead    = "api_keys:read"
+	AdminScopeAPIKeysWrite   = "api_keys:write" // 创建、吊销与轮换客户端API Key
+)
+
+// AdminScopes 全部权限范围，管理员账号与管理API token本身不能通过token管理
+var AdminScopes = []string{
+	AdminScopeSessionsRead,
+	AdminScopeSessionsWrite,
+	AdminScopeSessionsExport,
+	AdminScopeConfigRead,
+	AdminScopeConfigWrite,
+	AdminScopeAPIKeysRead,
+	AdminScopeAPIKeysWrite,
+}
+
+// IsValidAdminScope 检查权限范围是否合法
+func IsValidAdminScope(scope string) bool {
+	for _, s := range AdminScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrAdminAPITokenNotFound token不存在或不匹配
+	ErrAdminAPITokenNotFound = errors.New("admin api token not found")
+	// ErrAdminAPITokenRevoked token已被吊销
+	ErrAdminAPITokenRevoked = errors.New("admin api token has been revoked")
+	// ErrAdminAPITokenExpired token已过期
+	ErrAdminAPITokenExpired = errors.New("admin api token has expired")
+)
+
+// AdminAPIToken 供自动化脚本使用的管理API token，只能访问 Scopes 中的接口，只保存明文的 SHA-256
+type AdminAPIToken struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Hash       string    `json:"hash,omitempty"`
+	Prefix     string    `json:"prefix"`
+	Scopes     []string  `json:"scopes"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+// clone 返回不含哈希的副本，调用方需持有存储锁
+func (t *AdminAPIToken) clone() *AdminAPIToken {
+	tokenCopy := *t
+	tokenCopy.Hash = ""
+	tokenCopy.Scopes = append([]string(nil), t.Scopes...)
+	return &tokenCopy
+}
+
+// Status 返回 active、expired 或 revoked
+func (t *AdminAPIToken) Status() string {
+	switch {
+	case !t.RevokedAt.IsZero():
+		return "revoked"
+	case !t.ExpiresAt.IsZero() && time.Now().After(t.ExpiresAt):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// MarshalJSON 附带 status 字段
+func (t *AdminAPIToken) MarshalJSON() ([]byte, error) {
+	type adminAPIToken AdminAPIToken
+	return json.Marshal(struct {
+		adminAPIToken
+		Status string `json:"status"`
+	}{adminAPIToken(*t), t.Status()})
+}
+
+// HasScope token是否包含该权限范围
+func (t *AdminAPIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminAPITokenSpec 创建token时的参数
+type AdminAPITokenSpec struct {
+	Name      string
+	Scopes    []string
+	CreatedBy string
+	ExpiresAt time.Time // 零值表示不过期
+}
+
+// Validate 校验创建参数
+func (s AdminAPITokenSpec) Validate() error {
+	errs := &ValidationError{}
+	if strings.TrimSpace(s.Name) == "" {
+		errs.Add("name", "must not be empty")
+	}
+	if len(s.Scopes) == 0 {
+		errs.Add("scopes", "must not be empty")
+	}
+	for _, scope := range s.Scopes {
+		if !IsValidAdminScope(scope) {
+			errs.Add("scopes", "unknown scope %q (must be one of %s)", scope, strings.Join(AdminScopes, ", "))
+		}
+	}
+	if !s.ExpiresAt.IsZero() && !s.ExpiresAt.After(time.Now()) {
+		errs.Add("expiresAt", "must be in the future")
+	}
+	return errs.Err()
+}
+
+// AdminAPITokenStore 管理API token存储，保存在 JSON 文件中
+type AdminAPITokenStore struct {
+	path   string
+	tokens map[string]*AdminAPIToken // 按 ID 索引
+	byHash map[string]*AdminAPIToken
+	mu     sync.Mutex
+}
+
+// NewAdminAPITokenStore 从文件加载token，文件不存在时为空
+func NewAdminAPITokenStore(path string) (*AdminAPITokenStore, error) {
+	s := &AdminAPITokenStore{
+		path:   path,
+		tokens: make(map[string]*AdminAPIToken),
+		byHash: make(map[string]*AdminAPIToken),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		var tokens []*AdminAPIToken
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			return nil, fmt.Errorf("failed to parse admin api tokens file %s: %w", path, err)
+		}
+		for _, token := range tokens {
+			s.tokens[token.ID] = token
+			s.byHash[token.Hash] = token
+		}
+	}
+	return s, nil
+}
+
+// Create 创建token，返回记录与只在此时可见的明文
+func (s *AdminAPITokenStore) Create(spec AdminAPITokenSpec) (*AdminAPIToken, string, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, "", err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	random, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := AdminAPITokenPrefix + random
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := &AdminAPIToken{
+		ID:        "tok_" + id,
+		Name:      spec.Name,
+		Hash:      hashAPIKey(secret),
+		Prefix:    secret[:len(AdminAPITokenPrefix)+6],
+		Scopes:    append([]string(nil), spec.Scopes...),
+		CreatedBy: spec.CreatedBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: spec.ExpiresAt,
+	}
+	s.tokens[token.ID] = token
+	s.byHash[token.Hash] = token
+	return token.clone(), secret, s.saveLocked()
+}
+
+// List 按创建时间返回全部token
+func (s *AdminAPITokenStore) List() []*AdminAPIToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := make([]*AdminAPIToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token.clone())
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens
+}
+
+// Revoke 吊销token，立即失效；已吊销的token保持原吊销时间
+func (s *AdminAPITokenStore) Revoke(id string) (*AdminAPIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, exists := s.tokens[id]
+	if !exists {
+		return nil, ErrAdminAPITokenNotFound
+	}
+	if token.RevokedAt.IsZero() {
+		token.RevokedAt = time.Now()
+	}
+	return token.clone(), s.saveLocked()
+}
+
+// Authenticate 校验明文token并记录使用时间，最近使用时间最多每分钟写回一次
+func (s *AdminAPITokenStore) Authenticate(secret string) (*AdminAPIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, exists := s.byHash[hashAPIKey(secret)]
+	if !exists {
+		return nil, ErrAdminAPITokenNotFound
+	}
+	switch token.Status() {
+	case "revoked":
+		return nil, ErrAdminAPITokenRevoked
+	case "expired":
+		return nil, ErrAdminAPITokenExpired
+	}
+	now := time.Now()
+	if now.Sub(token.LastUsedAt) >= adminAPITokenTouchInterval {
+		token.LastUsedAt = now
+		if err := s.saveLocked(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to save admin api tokens: %v", err))
+		}
+	}
+	return token.clone(), nil
+}
+
+// saveLocked 写回文件，调用方需持有存储锁
+func (s *AdminAPITokenStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	tokens := make([]*AdminAPIToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0o600)
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"claude2api/logger"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// 管理端的认证方式
+const (
+	AdminAuthJWTOnly     = "jwt_only"      // 只接受登录签发的 JWT 与管理API token
+	AdminAuthJWTOrAPIKey = "jwt_or_apikey" // 兼容旧版：JWT 无效时也接受业务 apiKey，拥有 admin 权限
+	AdminAuthMTLS        = "mtls"          // 另外要求经 tls.clientCAFile 验证的客户端证书
+)
+
+// TLSConfig 直接提供 HTTPS 时的证书配置，clientCAFile 用于验证管理端的客户端证书
+type TLSConfig struct {
+	CertFile     string `yaml:"certFile"`
+	KeyFile      string `yaml:"keyFile"`
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+// Enabled 是否配置了服务端证书
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// ServerTLSConfig 返回 HTTP 服务使用的 TLS 配置
+// 配置了 clientCAFile 时验证客户端提供的证书，但不强制提供，业务 API 不受影响
+func (t TLSConfig) ServerTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+	data, err := os.ReadFile(t.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", t.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsConfig, nil
+}
+
+// IsValidAdminAuth 检查管理端认证方式是否合法
+func IsValidAdminAuth(mode string) bool {
+	switch mode {
+	case AdminAuthJWTOnly, AdminAuthJWTOrAPIKey, AdminAuthMTLS:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetAdminAuth 获取管理端认证方式，未配置时为 jwt_only
+func (c *Config) GetAdminAuth() string {
+	c.RwMutx.RLock()
+	defer c.RwMutx.RUnlock()
+	if c.AdminAuth == "" {
+		return AdminAuthJWTOnly
+	}
+	return c.AdminAuth
+}
+
+// validateAdminAuth 校验认证方式，mtls 需要服务端证书与客户端 CA
+func (c *Config) validateAdminAuth() error {
+	if c.AdminAuth == "" {
+		return nil
+	}
+	if !IsValidAdminAuth(c.AdminAuth) {
+		return fmt.Errorf("invalid adminAuth: %s (must be %s, %s or %s)", c.AdminAuth, AdminAuthJWTOnly, AdminAuthJWTOrAPIKey, AdminAuthMTLS)
+	}
+	if c.AdminAuth == AdminAuthMTLS && (!c.TLS.Enabled() || c.TLS.ClientCAFile == "") {
+		return fmt.Errorf("adminAuth %s requires tls.certFile, tls.keyFile and tls.clientCAFile", AdminAuthMTLS)
+	}
+	return nil
+}
+
+// validateAdminAuthReload 热加载时只有运行中的监听器已经验证客户端证书才能切换到 mtls，
+// 否则所有管理接口（包括登录）都会返回 403；tls 修改后需要重启，当前值即运行中的配置
+func (c *Config) validateAdminAuthReload(mode string) error {
+	if mode != AdminAuthMTLS {
+		return nil
+	}
+	c.RwMutx.RLock()
+	defer c.RwMutx.RUnlock()
+	if c.AdminAuth == AdminAuthMTLS || (c.TLS.Enabled() && c.TLS.ClientCAFile != "") {
+		return nil
+	}
+	errs := &ValidationError{}
+	errs.Add("adminAuth", "cannot switch to %s while the server is running without tls.clientCAFile, restart to enable it", AdminAuthMTLS)
+	return errs.Err()
+}
+
+// warnAdminAuth 提示管理端接受业务 apiKey 的风险
+func (c *Config) warnAdminAuth() {
+	if c.GetAdminAuth() == AdminAuthJWTOrAPIKey {
+		logger.Warn("adminAuth is jwt_or_apikey: anyone with the business API key has full admin access; use jwt_only and scoped admin API tokens instead")
+	}
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"claude2api/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAdminTokensFile      = "data/admin_tokens.json"
+	defaultAdminAccessTokenTTL  = 15 * time.Minute
+	defaultAdminRefreshTokenTTL = 7 * 24 * time.Hour
+	// 刷新token的明文前缀
+	adminRefreshTokenPrefix = "c2r-"
+)
+
+var (
+	// ErrRefreshTokenInvalid 刷新token不存在、已过期或所属登录已退出
+	ErrRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused 已轮换的刷新token被再次使用，整个登录随之吊销
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrAdminTokenRevoked 访问token或其所属登录已被吊销
+	ErrAdminTokenRevoked = errors.New("token has been revoked")
+)
+
+// AdminTokenFamily 一次登录签发的token族：登录时创建，每次刷新轮换刷新token，
+// 退出登录时整族吊销，族内签发的访问token随之失效
+type AdminTokenFamily struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	CreatedAt     time.Time `json:"created_at"`
+	RefreshedAt   time.Time `json:"refreshed_at,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"` // 当前刷新token的过期时间，此后族内没有有效的token
+	RevokedAt     time.Time `json:"revoked_at,omitempty"`
+	RefreshHash   string    `json:"refresh_hash"`             // 当前刷新token的 SHA-256
+	RotatedHashes []string  `json:"rotated_hashes,omitempty"` // 已轮换的刷新token，再次出现时视为泄露
+}
+
+// adminTokensFile 持久化的token状态
+type adminTokensFile struct {
+	Families      []*AdminTokenFamily  `json:"families"`
+	RevokedTokens map[string]time.Time `json:"revoked_tokens"` // 吊销的访问token jti 及其过期时间
+}
+
+// AdminTokenStore 管理端登录的刷新token与吊销列表，保存在 JSON 文件中，重启后吊销仍然有效
+type AdminTokenStore struct {
+	path     string
+	families map[string]*AdminTokenFamily
+	byHash   map[string]*AdminTokenFamily // 当前与已轮换的刷新token
+	revoked  map[string]time.Time
+	mu       sync.Mutex
+}
+
+// NewAdminTokenStore 从文件加载token状态，文件不存在时为空
+func NewAdminTokenStore(path string) (*AdminTokenStore, error) {
+	s := &AdminTokenStore{
+		path:     path,
+		families: make(map[string]*AdminTokenFamily),
+		byHash:   make(map[string]*AdminTokenFamily),
+		revoked:  make(map[string]time.Time),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		var file adminTokensFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse admin tokens file %s: %w", path, err)
+		}
+		for _, family := range file.Families {
+			s.addFamilyLocked(family)
+		}
+		for jti, expiresAt := range file.RevokedTokens {
+			s.revoked[jti] = expiresAt
+		}
+	}
+	s.pruneLocked(time.Now())
+	return s, nil
+}
+
+// addFamilyLocked 索引token族，调用方需持有存储锁
+func (s *AdminTokenStore) addFamilyLocked(family *AdminTokenFamily) {
+	s.families[family.ID] = family
+	s.byHash[family.RefreshHash] = family
+	for _, hash := range family.RotatedHashes {
+		s.byHash[hash] = family
+	}
+}
+
+// newRefreshToken 生成刷新token的明文
+func newRefreshToken() (string, error) {
+	random, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	return adminRefreshTokenPrefix + random, nil
+}
+
+// CreateFamily 为一次登录创建token族，返回族与刷新token的明文，写回失败时不创建
+func (s *AdminTokenStore) CreateFamily(username string, refreshTTL time.Duration) (*AdminTokenFamily, string, error) {
+	id, err := randomHex(12)
+	if err != nil {
+		return nil, "", err
+	}
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.pruneLocked(now)
+	family := &AdminTokenFamily{
+		ID:          id,
+		Username:    username,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(refreshTTL),
+		RefreshHash: hashAPIKey(refresh),
+	}
+	s.addFamilyLocked(family)
+	if err := s.saveLocked(); err != nil {
+		delete(s.families, family.ID)
+		delete(s.byHash, family.RefreshHash)
+		return nil, "", err
+	}
+	familyCopy := *family
+	return &familyCopy, refresh, nil
+}
+
+// Rotate 用刷新token换取新的刷新token，旧的立即失效
+// 已轮换的刷新token再次出现说明可能已泄露，整族吊销并返回 ErrRefreshTokenReused
+// 写回失败时撤销轮换，旧的刷新token仍然有效，避免重启后新签发的token被拒绝
+func (s *AdminTokenStore) Rotate(refresh string, refreshTTL time.Duration) (*AdminTokenFamily, string, error) {
+	next, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	hash := hashAPIKey(refresh)
+	family, exists := s.byHash[hash]
+	if !exists || !family.RevokedAt.IsZero() || now.After(family.ExpiresAt) {
+		return nil, "", ErrRefreshTokenInvalid
+	}
+	if family.RefreshHash != hash {
+		family.RevokedAt = now
+		if err := s.saveLocked(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to save admin tokens: %v", err))
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	previous := *family
+	family.RotatedHashes = append(family.RotatedHashes, family.RefreshHash)
+	family.RefreshHash = hashAPIKey(next)
+	family.RefreshedAt = now
+	family.ExpiresAt = now.Add(refreshTTL)
+	s.byHash[family.RefreshHash] = family
+	if err := s.saveLocked(); err != nil {
+		delete(s.byHash, family.RefreshHash)
+		*family = previous
+		return nil, "", err
+	}
+	familyCopy := *family
+	return &familyCopy, next, nil
+}
+
+// CheckAccess 检查访问token的 jti 与所属token族是否已被吊销
+func (s *AdminTokenStore) CheckAccess(jti, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, revoked := s.revoked[jti]; revoked {
+		return ErrAdminTokenRevoked
+	}
+	family, exists := s.families[familyID]
+	if !exists || !family.RevokedAt.IsZero() {
+		return ErrAdminTokenRevoked
+	}
+	return nil
+}
+
+// RevokeFamily 吊销访问token与其所属的token族（退出登录）
+// jti 为空时只吊销token族；expiresAt 为访问token的过期时间，之后可以从吊销列表中移除
+func (s *AdminTokenStore) RevokeFamily(familyID, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if jti != "" {
+		s.revoked[jti] = expiresAt
+	}
+	if family, exists := s.families[familyID]; exists && family.RevokedAt.IsZero() {
+		family.RevokedAt = now
+	}
+	return s.saveLocked()
+}
+
+// RevokeRefreshToken 吊销刷新token所属的token族，用于访问token已过期时退出登录
+func (s *AdminTokenStore) RevokeRefreshToken(refresh string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	family, exists := s.byHash[hashAPIKey(refresh)]
+	if !exists {
+		return ErrRefreshTokenInvalid
+	}
+	if family.RevokedAt.IsZero() {
+		family.RevokedAt = time.Now()
+	}
+	return s.saveLocked()
+}
+
+// RevokeUser 吊销账号的全部token族（退出所有登录），返回吊销的数量
+func (s *AdminTokenStore) RevokeUser(username string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	count := 0
+	for _, family := range s.families {
+		if family.Username == username && family.RevokedAt.IsZero() && now.Before(family.ExpiresAt) {
+			family.RevokedAt = now
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return count, s.saveLocked()
+}
+
+// ActiveFamilies 返回账号当前有效的登录，按创建时间排序
+func (s *AdminTokenStore) ActiveFamilies(username string) []*AdminTokenFamily {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	families := make([]*AdminTokenFamily, 0)
+	for _, family := range s.families {
+		if family.Username == username && family.RevokedAt.IsZero() && now.Before(family.ExpiresAt) {
+			familyCopy := *family
+			familyCopy.RefreshHash = ""
+			familyCopy.RotatedHashes = nil
+			families = append(families, &familyCopy)
+		}
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].CreatedAt.Before(families[j].CreatedAt)
+	})
+	return families
+}
+
+// pruneLocked 移除已过期的token族与吊销记录，其中的token都已过期，调用方需持有存储锁
+func (s *AdminTokenStore) pruneLocked(now time.Time) {
+	for id, family := range s.families {
+		if now.After(family.ExpiresAt) {
+			delete(s.byHash, family.RefreshHash)
+			for _, hash := range family.RotatedHashes {
+				delete(s.byHash, hash)
+			}
+			delete(s.families, id)
+		}
+	}
+	for jti, expiresAt := range s.revoked {
+		if now.After(expiresAt) {
+			delete(s.revoked, jti)
+		}
+	}
+}
+
+// saveLocked 写回文件，调用方需持有存储锁
+func (s *AdminTokenStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	s.pruneLocked(time.Now())
+	file := adminTokensFile{
+		Families:      make([]*AdminTokenFamily, 0, len(s.families)),
+		RevokedTokens: s.revoked,
+	}
+	for _, family := range s.families {
+		file.Families = append(file.Families, family)
+	}
+	sort.Slice(file.Families, func(i, j int) bool {
+		return file.Families[i].CreatedAt.Before(file.Families[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0o600)
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdminTokenStoreRotate(t *testing.T) {
+	tests := []struct {
+		name        string
+		logout      bool   // 第二次刷新前退出登录
+		present     string // 第二次刷新提交的token：initial、rotated 或 unknown
+		wantErr     error
+		wantRevoked bool
+	}{
+		{name: "current token rotates", present: "rotated"},
+		{name: "rotated token reuse revokes family", present: "initial", wantErr: ErrRefreshTokenReused, wantRevoked: true},
+		{name: "unknown token", present: "unknown", wantErr: ErrRefreshTokenInvalid},
+		{name: "logged out family", logout: true, present: "rotated", wantErr: ErrRefreshTokenInvalid, wantRevoked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewAdminTokenStore("")
+			if err != nil {
+				t.Fatalf("NewAdminTokenStore: %v", err)
+			}
+			family, initial, err := store.CreateFamily("admin", time.Hour)
+			if err != nil {
+				t.Fatalf("CreateFamily: %v", err)
+			}
+			_, rotated, err := store.Rotate(initial, time.Hour)
+			if err != nil {
+				t.Fatalf("first Rotate: %v", err)
+			}
+			if rotated == initial {
+				t.Fatal("Rotate returned the same refresh token")
+			}
+			if tt.logout {
+				if err := store.RevokeFamily(family.ID, "", time.Time{}); err != nil {
+					t.Fatalf("RevokeFamily: %v", err)
+				}
+			}
+
+			present := map[string]string{"initial": initial, "rotated": rotated, "unknown": adminRefreshTokenPrefix + "unknown"}[tt.present]
+			_, next, err := store.Rotate(present, time.Hour)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rotate error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && next == "" {
+				t.Fatal("Rotate returned an empty refresh token")
+			}
+
+			accessErr := store.CheckAccess("jti", family.ID)
+			if revoked := errors.Is(accessErr, ErrAdminTokenRevoked); revoked != tt.wantRevoked {
+				t.Fatalf("family revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+			if tt.wantRevoked {
+				// 整族吊销后，合法持有者手中最新的刷新token也不能再用
+				if _, _, err := store.Rotate(rotated, time.Hour); !errors.Is(err, ErrRefreshTokenInvalid) {
+					t.Fatalf("Rotate after revocation error = %v, want %v", err, ErrRefreshTokenInvalid)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminTokenStoreRotateExpired(t *testing.T) {
+	store, err := NewAdminTokenStore("")
+	if err != nil {
+		t.Fatalf("NewAdminTokenStore: %v", err)
+	}
+	_, refresh, err := store.CreateFamily("admin", -time.Second)
+	if err != nil {
+		t.Fatalf("CreateFamily: %v", err)
+	}
+	if _, _, err := store.Rotate(refresh, time.Hour); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("Rotate error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+func TestAdminTokenStoreReuseDetectedAfterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin_tokens.json")
+	store, err := NewAdminTokenStore(path)
+	if err != nil {
+		t.Fatalf("NewAdminTokenStore: %v", err)
+	}
+	family, initial, err := store.CreateFamily("admin", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateFamily: %v", err)
+	}
+	if _, _, err := store.Rotate(initial, time.Hour); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	reloaded, err := NewAdminTokenStore(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if _, _, err := reloaded.Rotate(initial, time.Hour); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Rotate error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if err := reloaded.CheckAccess("jti", family.ID); !errors.Is(err, ErrAdminTokenRevoked) {
+		t.Fatalf("CheckAccess error = %v, want %v", err, ErrAdminTokenRevoked)
+	}
+}
+
+func TestAdminTokenStoreRollsBackUnsavedChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin_tokens.json")
+	store, err := NewAdminTokenStore(path)
+	if err != nil {
+		t.Fatalf("NewAdminTokenStore: %v", err)
+	}
+	family, refresh, err := store.CreateFamily("admin", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateFamily: %v", err)
+	}
+	// 文件位置被目录占用，原子替换失败
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if created, _, err := store.CreateFamily("admin", time.Hour); err == nil || created != nil {
+		t.Fatalf("CreateFamily = %v, %v, want the save error", created, err)
+	}
+	if families := store.ActiveFamilies("admin"); len(families) != 1 {
+		t.Fatalf("store has %d active families, want 1", len(families))
+	}
+	if rotated, _, err := store.Rotate(refresh, time.Hour); err == nil || rotated != nil {
+		t.Fatalf("Rotate = %v, %v, want the save error", rotated, err)
+	}
+
+	// 未写回的轮换被撤销，原来的刷新token仍是当前token
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Rotate(refresh, time.Hour); err != nil {
+		t.Fatalf("Rotate after failed save: %v", err)
+	}
+	if err := store.CheckAccess("jti", family.ID); err != nil {
+		t.Fatalf("CheckAccess: %v", err)
+	}
+}
@@ -0,0 +1,371 @@
+package config
+
+import (
+	"claude2api/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultAdminUsersFile = "data/admin_users.json"
+	// DefaultAdminPassword 未配置 adminPassword 时首个管理员的密码，首次登录后必须修改
+	DefaultAdminPassword = "admin123"
+	// 管理员密码的最小长度
+	minAdminPasswordLength = 8
+)
+
+// 管理员角色，权限依次递增
+const (
+	AdminRoleViewer   = "viewer"   // 只读：查看sessions、统计、配置与API Key
+	AdminRoleOperator = "operator" // 管理sessions：添加、修改、删除、导入导出与状态切换
+	AdminRoleAdmin    = "admin"    // 全部权限：修改配置、管理API Key与管理员账号
+)
+
+var adminRoleRanks = map[string]int{
+	AdminRoleViewer:   1,
+	AdminRoleOperator: 2,
+	AdminRoleAdmin:    3,
+}
+
+var adminUsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.@-]{1,64}$`)
+
+var (
+	// ErrAdminUserNotFound 管理员账号不存在
+	ErrAdminUserNotFound = errors.New("admin user not found")
+	// ErrAdminUserExists 用户名已被使用
+	ErrAdminUserExists = errors.New("admin user already exists")
+	// ErrInvalidAdminCredentials 用户名或密码错误
+	ErrInvalidAdminCredentials = errors.New("invalid username or password")
+	// ErrLastAdmin 不能删除最后一个 admin 角色的账号
+	ErrLastAdmin = errors.New("cannot remove the last admin user")
+)
+
+// IsValidAdminRole 检查管理员角色是否合法
+func IsValidAdminRole(role string) bool {
+	_, ok := adminRoleRanks[role]
+	return ok
+}
+
+// AdminRoleAllows 角色 role 是否具有 required 角色的权限
+func AdminRoleAllows(role, required string) bool {
+	rank, ok := adminRoleRanks[role]
+	return ok && rank >= adminRoleRanks[required]
+}
+
+// AdminUser 管理员账号，只保存密码的 bcrypt 哈希
+type AdminUser struct {
+	Username           string    `json:"username"`
+	PasswordHash       string    `json:"password_hash,omitempty"`
+	Role               string    `json:"role"`
+	MustChangePassword bool      `json:"must_change_password"` // 修改密码前只能访问 /admin/me
+	CreatedAt          time.Time `json:"created_at"`
+	PasswordChangedAt  time.Time `json:"password_changed_at"` // 此前签发的token失效
+	LastLoginAt        time.Time `json:"last_login_at,omitempty"`
+}
+
+// clone 返回不含哈希的副本，调用方需持有存储锁
+func (u *AdminUser) clone() *AdminUser {
+	userCopy := *u
+	userCopy.PasswordHash = ""
+	return &userCopy
+}
+
+// AdminUserSpec 创建管理员账号时的参数
+type AdminUserSpec struct {
+	Username           string
+	Password           string
+	Role               string
+	MustChangePassword bool // 由他人设置的初始密码，账号首次登录后需修改
+}
+
+// Validate 校验创建参数
+func (s AdminUserSpec) Validate() error {
+	errs := &ValidationError{}
+	if !adminUsernamePattern.MatchString(s.Username) {
+		errs.Add("username", "must be 1-64 letters, digits or _ . @ -")
+	}
+	validateAdminPassword(errs, "password", s.Password)
+	if !IsValidAdminRole(s.Role) {
+		errs.Add("role", "must be one of %s, %s, %s", AdminRoleViewer, AdminRoleOperator, AdminRoleAdmin)
+	}
+	return errs.Err()
+}
+
+// validateAdminPassword 检查密码长度，且不能使用默认密码
+func validateAdminPassword(errs *ValidationError, field, password string) {
+	if len(password) < minAdminPasswordLength {
+		errs.Add(field, "must be at least %d characters", minAdminPasswordLength)
+	} else if password == DefaultAdminPassword {
+		errs.Add(field, "must not be the default password")
+	}
+	if len(password) > 72 {
+		// bcrypt 只使用前 72 字节
+		errs.Add(field, "must be at most 72 bytes")
+	}
+}
+
+// AdminUserStore 管理员账号存储，保存在 JSON 文件中
+type AdminUserStore struct {
+	path      string
+	users     map[string]*AdminUser
+	mu        sync.Mutex
+	dummyHash []byte // 用户不存在时也做一次哈希比较，避免通过响应时间探测用户名
+}
+
+// NewAdminUserStore 从文件加载管理员账号
+// 没有任何账号时用 bootstrapUser/bootstrapPassword 创建首个 admin 账号，
+// 使用默认密码时账号必须先修改密码，管理API才会解锁
+func NewAdminUserStore(path, bootstrapUser, bootstrapPassword string) (*AdminUserStore, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte(DefaultAdminPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	s := &AdminUserStore{
+		path:      path,
+		users:     make(map[string]*AdminUser),
+		dummyHash: dummyHash,
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		var users []*AdminUser
+		if err := json.Unmarshal(data, &users); err != nil {
+			return nil, fmt.Errorf("failed to parse admin users file %s: %w", path, err)
+		}
+		for _, user := range users {
+			s.users[user.Username] = user
+		}
+	}
+	if len(s.users) > 0 {
+		return s, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(bootstrapPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s.users[bootstrapUser] = &AdminUser{
+		Username:           bootstrapUser,
+		PasswordHash:       string(hash),
+		Role:               AdminRoleAdmin,
+		MustChangePassword: bootstrapPassword == DefaultAdminPassword,
+		CreatedAt:          now,
+		PasswordChangedAt:  now,
+	}
+	logger.Info(fmt.Sprintf("Created admin user %q from adminUser/adminPassword", bootstrapUser))
+	if err := s.saveLocked(); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save admin users: %v", err))
+	}
+	return s, nil
+}
+
+// Authenticate 校验用户名与密码并记录登录时间
+func (s *AdminUserStore) Authenticate(username, password string) (*AdminUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, err := s.verifyLocked(username, password)
+	if err != nil {
+		return nil, err
+	}
+	user.LastLoginAt = time.Now()
+	if err := s.saveLocked(); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save admin users: %v", err))
+	}
+	return user.clone(), nil
+}
+
+// verifyLocked 校验用户名与密码，调用方需持有存储锁
+func (s *AdminUserStore) verifyLocked(username, password string) (*AdminUser, error) {
+	user, exists := s.users[username]
+	if !exists {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, ErrInvalidAdminCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidAdminCredentials
+	}
+	return user, nil
+}
+
+// Get 返回指定的管理员账号
+func (s *AdminUserStore) Get(username string) (*AdminUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, exists := s.users[username]
+	if !exists {
+		return nil, ErrAdminUserNotFound
+	}
+	return user.clone(), nil
+}
+
+// List 按创建时间返回全部管理员账号
+func (s *AdminUserStore) List() []*AdminUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]*AdminUser, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user.clone())
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+	return users
+}
+
+// Create 创建管理员账号
+func (s *AdminUserStore) Create(spec AdminUserSpec) (*AdminUser, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(spec.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.users[spec.Username]; exists {
+		return nil, ErrAdminUserExists
+	}
+	now := time.Now()
+	user := &AdminUser{
+		Username:           spec.Username,
+		PasswordHash:       string(hash),
+		Role:               spec.Role,
+		MustChangePassword: spec.MustChangePassword,
+		CreatedAt:          now,
+		PasswordChangedAt:  now,
+	}
+	s.users[user.Username] = user
+	return user.clone(), s.saveLocked()
+}
+
+// Delete 删除管理员账号，不能删除最后一个 admin 角色的账号
+func (s *AdminUserStore) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, exists := s.users[username]
+	if !exists {
+		return ErrAdminUserNotFound
+	}
+	if user.Role == AdminRoleAdmin && s.countRoleLocked(AdminRoleAdmin) == 1 {
+		return ErrLastAdmin
+	}
+	delete(s.users, username)
+	return s.saveLocked()
+}
+
+// SetRole 修改账号角色，不能降级最后一个 admin 角色的账号
+func (s *AdminUserStore) SetRole(username, role string) (*AdminUser, error) {
+	if !IsValidAdminRole(role) {
+		errs := &ValidationError{}
+		errs.Add("role", "must be one of %s, %s, %s", AdminRoleViewer, AdminRoleOperator, AdminRoleAdmin)
+		return nil, errs.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, exists := s.users[username]
+	if !exists {
+		return nil, ErrAdminUserNotFound
+	}
+	if user.Role == AdminRoleAdmin && role != AdminRoleAdmin && s.countRoleLocked(AdminRoleAdmin) == 1 {
+		return nil, ErrLastAdmin
+	}
+	user.Role = role
+	return user.clone(), s.saveLocked()
+}
+
+// ChangePassword 账号本人修改密码，需要提供当前密码
+func (s *AdminUserStore) ChangePassword(username, currentPassword, newPassword string) (*AdminUser, error) {
+	errs := &ValidationError{}
+	validateAdminPassword(errs, "newPassword", newPassword)
+	if currentPassword == newPassword {
+		errs.Add("newPassword", "must differ from the current password")
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, err := s.verifyLocked(username, currentPassword)
+	if err != nil {
+		return nil, err
+	}
+	s.setPasswordLocked(user, hash, false)
+	return user.clone(), s.saveLocked()
+}
+
+// ResetPassword 由 admin 为其他账号设置新密码，账号下次登录后需修改
+func (s *AdminUserStore) ResetPassword(username, newPassword string) (*AdminUser, error) {
+	errs := &ValidationError{}
+	validateAdminPassword(errs, "password", newPassword)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, exists := s.users[username]
+	if !exists {
+		return nil, ErrAdminUserNotFound
+	}
+	s.setPasswordLocked(user, hash, true)
+	return user.clone(), s.saveLocked()
+}
+
+// setPasswordLocked 替换密码哈希，此前签发的token随之失效，调用方需持有存储锁
+func (s *AdminUserStore) setPasswordLocked(user *AdminUser, hash []byte, mustChange bool) {
+	user.PasswordHash = string(hash)
+	user.MustChangePassword = mustChange
+	user.PasswordChangedAt = time.Now()
+}
+
+// countRoleLocked 返回指定角色的账号数，调用方需持有存储锁
+func (s *AdminUserStore) countRoleLocked(role string) int {
+	count := 0
+	for _, user := range s.users {
+		if user.Role == role {
+			count++
+		}
+	}
+	return count
+}
+
+// saveLocked 写回文件，调用方需持有存储锁
+func (s *AdminUserStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	users := make([]*AdminUser, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0o600)
+}
@@ -0,0 +1,503 @@
+package config
+
+import (
+	"claude2api/logger"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAPIKeysFile = "data/api_keys.json"
+	// 客户端Key的明文前缀，便于在日志与密钥扫描中识别
+	apiKeySecretPrefix = "c2a-"
+	// 用量计数写回文件的间隔，创建、吊销与轮换立即写入
+	apiKeyFlushInterval = time.Minute
+
+	// DefaultAPIKeyID 配置文件中 apiKey 对应的身份
+	DefaultAPIKeyID = "default"
+)
+
+var (
+	// ErrAPIKeyNotFound Key不存在或不匹配
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrAPIKeyRevoked Key已被吊销
+	ErrAPIKeyRevoked = errors.New("api key has been revoked")
+	// ErrAPIKeyExpired Key已过期
+	ErrAPIKeyExpired = errors.New("api key has expired")
+	// ErrAPIKeyRateLimited 超过每分钟请求数
+	ErrAPIKeyRateLimited = errors.New("api key requests per minute exceeded")
+	// ErrAPIKeyBudgetExhausted 当天的token预算已用完
+	ErrAPIKeyBudgetExhausted = errors.New("api key daily token budget exhausted")
+)
+
+// APIKeyUsage 客户端Key的用量计数，token 数为估算值
+type APIKeyUsage struct {
+	TotalRequests int64     `json:"total_requests"`
+	TotalTokens   int64     `json:"total_tokens"`
+	Day           string    `json:"day"` // 当日计数所属的日期（本地时间）
+	DailyRequests int64     `json:"daily_requests"`
+	DailyTokens   int64     `json:"daily_tokens"`
+	LastUsed      time.Time `json:"last_used,omitempty"`
+}
+
+// APIKey 注册表中的客户端Key，只保存明文的 SHA-256
+type APIKey struct {
+	ID                string      `json:"id"`
+	Name              string      `json:"name"`
+	Hash              string      `json:"hash,omitempty"`
+	Prefix            string      `json:"prefix"` // 明文开头几位，用于辨认
+	AllowedModels     []string    `json:"allowed_models,omitempty"`
+	RequestsPerMinute int         `json:"requests_per_minute,omitempty"`
+	DailyTokenBudget  int64       `json:"daily_token_budget,omitempty"`
+	CreatedAt         time.Time   `json:"created_at"`
+	ExpiresAt         time.Time   `json:"expires_at,omitempty"`
+	RevokedAt         time.Time   `json:"revoked_at,omitempty"`
+	RotatedAt         time.Time   `json:"rotated_at,omitempty"`
+	Usage             APIKeyUsage `json:"usage"`
+
+	minuteStart time.Time // 当前一分钟窗口的开始时间
+	minuteCount int       // 当前窗口内的请求数
+}
+
+// APIKeySpec 创建Key时的参数
+type APIKeySpec struct {
+	Name              string
+	AllowedModels     []string
+	RequestsPerMinute int
+	DailyTokenBudget  int64
+	ExpiresAt         time.Time // 零值表示不过期
+}
+
+// Validate 校验创建参数
+func (s APIKeySpec) Validate() error {
+	errs := &ValidationError{}
+	if strings.TrimSpace(s.Name) == "" {
+		errs.Add("name", "must not be empty")
+	}
+	for _, pattern := range s.AllowedModels {
+		if !isValidModelPattern(pattern) {
+			errs.Add("allowedModels", "invalid model pattern %q", pattern)
+		}
+	}
+	if s.RequestsPerMinute < 0 {
+		errs.Add("requestsPerMinute", "must be non-negative")
+	}
+	if s.DailyTokenBudget < 0 {
+		errs.Add("dailyTokenBudget", "must be non-negative")
+	}
+	if !s.ExpiresAt.IsZero() && !s.ExpiresAt.After(time.Now()) {
+		errs.Add("expiresAt", "must be in the future")
+	}
+	return errs.Err()
+}
+
+// APIKeyIdentity 请求使用的Key身份，由 AuthMiddleware 写入 gin 上下文
+type APIKeyIdentity struct {
+	ID            string
+	Name          string
+	AllowedModels []string
+}
+
+// AllowsModel Key是否可以使用该模型，未限制模型时总是允许
+func (id *APIKeyIdentity) AllowsModel(model string) bool {
+	if len(id.AllowedModels) == 0 {
+		return true
+	}
+	for _, pattern := range id.AllowedModels {
+		if matchModel(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultAPIKeyIdentity 配置文件中 apiKey 的身份，不受模型与配额限制
+func DefaultAPIKeyIdentity() *APIKeyIdentity {
+	return &APIKeyIdentity{ID: DefaultAPIKeyID, Name: DefaultAPIKeyID}
+}
+
+// clone 返回不含哈希的副本，调用方需持有注册表锁
+func (k *APIKey) clone() *APIKey {
+	keyCopy := *k
+	keyCopy.Hash = ""
+	keyCopy.AllowedModels = append([]string(nil), k.AllowedModels...)
+	return &keyCopy
+}
+
+// Status 返回 active、expired 或 revoked
+func (k *APIKey) Status() string {
+	switch {
+	case !k.RevokedAt.IsZero():
+		return "revoked"
+	case !k.ExpiresAt.IsZero() && time.Now().After(k.ExpiresAt):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// MarshalJSON 附带 status 字段
+func (k *APIKey) MarshalJSON() ([]byte, error) {
+	type apiKey APIKey
+	return json.Marshal(struct {
+		apiKey
+		Status string `json:"status"`
+	}{apiKey(*k), k.Status()})
+}
+
+// rollDay 跨天时清零当日计数，调用方需持有注册表锁
+func (u *APIKeyUsage) rollDay(now time.Time) {
+	if day := now.Format("2006-01-02"); u.Day != day {
+		u.Day = day
+		u.DailyRequests = 0
+		u.DailyTokens = 0
+	}
+}
+
+// APIKeyRegistry 客户端Key注册表，保存在 JSON 文件中
+type APIKeyRegistry struct {
+	path      string
+	keys      map[string]*APIKey // 按 ID 索引
+	byHash    map[string]*APIKey
+	dirty     bool // 用量有未写回的变化
+	mu        sync.Mutex
+	stopFlush chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAPIKeyRegistry 从文件加载注册表并启动用量的定期写回，文件不存在时为空注册表
+func NewAPIKeyRegistry(path string) (*APIKeyRegistry, error) {
+	r := &APIKeyRegistry{
+		path:      path,
+		keys:      make(map[string]*APIKey),
+		byHash:    make(map[string]*APIKey),
+		stopFlush: make(chan struct{}),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		var keys []*APIKey
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse api keys file %s: %w", path, err)
+		}
+		for _, key := range keys {
+			r.keys[key.ID] = key
+			r.byHash[key.Hash] = key
+		}
+	}
+	go r.runFlush()
+	return r, nil
+}
+
+// hashAPIKey 返回明文Key的 SHA-256
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex 返回 n 字节的随机十六进制串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// newSecret 生成新的明文Key
+func newSecret() (string, error) {
+	random, err := randomHex(24)
+	if err != nil {
+		return "", err
+	}
+	return apiKeySecretPrefix + random, nil
+}
+
+// setSecretLocked 替换Key的明文，调用方需持有注册表锁
+func (r *APIKeyRegistry) setSecretLocked(key *APIKey, secret string) {
+	delete(r.byHash, key.Hash)
+	key.Hash = hashAPIKey(secret)
+	key.Prefix = secret[:len(apiKeySecretPrefix)+6]
+	r.byHash[key.Hash] = key
+}
+
+// Create 创建Key，返回记录与只在此时可见的明文
+func (r *APIKeyRegistry) Create(spec APIKeySpec) (*APIKey, string, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, "", err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := &APIKey{
+		ID:                "key_" + id,
+		Name:              spec.Name,
+		AllowedModels:     append([]string(nil), spec.AllowedModels...),
+		RequestsPerMinute: spec.RequestsPerMinute,
+		DailyTokenBudget:  spec.DailyTokenBudget,
+		CreatedAt:         time.Now(),
+		ExpiresAt:         spec.ExpiresAt,
+	}
+	r.setSecretLocked(key, secret)
+	r.keys[key.ID] = key
+	// 写回失败时撤销创建，避免返回给调用方的明文在重启后失效
+	if err := r.saveLocked(); err != nil {
+		delete(r.byHash, key.Hash)
+		delete(r.keys, key.ID)
+		return nil, "", err
+	}
+	return key.clone(), secret, nil
+}
+
+// List 按创建时间返回全部Key
+func (r *APIKeyRegistry) List() []*APIKey {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	keys := make([]*APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		key.Usage.rollDay(now)
+		keys = append(keys, key.clone())
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// Get 返回指定的Key
+func (r *APIKeyRegistry) Get(id string) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, exists := r.keys[id]
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+	key.Usage.rollDay(time.Now())
+	return key.clone(), nil
+}
+
+// Revoke 吊销Key，立即失效；已吊销的Key保持原吊销时间
+// 写回失败时撤销吊销，避免重启后Key意外恢复可用
+func (r *APIKeyRegistry) Revoke(id string) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, exists := r.keys[id]
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+	revokedAt := key.RevokedAt
+	if key.RevokedAt.IsZero() {
+		key.RevokedAt = time.Now()
+	}
+	if err := r.saveLocked(); err != nil {
+		key.RevokedAt = revokedAt
+		return nil, err
+	}
+	return key.clone(), nil
+}
+
+// Rotate 为Key生成新的明文，旧明文立即失效，ID、限制与用量保持不变
+// 写回失败时恢复旧明文，避免返回的新明文在重启后失效
+func (r *APIKeyRegistry) Rotate(id string) (*APIKey, string, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, exists := r.keys[id]
+	if !exists {
+		return nil, "", ErrAPIKeyNotFound
+	}
+	if !key.RevokedAt.IsZero() {
+		return nil, "", ErrAPIKeyRevoked
+	}
+	hash, prefix, rotatedAt := key.Hash, key.Prefix, key.RotatedAt
+	r.setSecretLocked(key, secret)
+	key.RotatedAt = time.Now()
+	if err := r.saveLocked(); err != nil {
+		delete(r.byHash, key.Hash)
+		key.Hash, key.Prefix, key.RotatedAt = hash, prefix, rotatedAt
+		r.byHash[key.Hash] = key
+		return nil, "", err
+	}
+	return key.clone(), secret, nil
+}
+
+// Authenticate 校验明文Key：检查吊销、过期、每分钟请求数与当日token预算
+// 校验通过时占用一次每分钟请求数，处理函数拒绝该请求时由 ReleaseRequest 退回；
+// 用量中的请求数在处理函数完成授权后由 RecordRequest 计入
+func (r *APIKeyRegistry) Authenticate(secret string) (*APIKeyIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, exists := r.byHash[hashAPIKey(secret)]
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+	switch key.Status() {
+	case "revoked":
+		return nil, ErrAPIKeyRevoked
+	case "expired":
+		return nil, ErrAPIKeyExpired
+	}
+
+	now := time.Now()
+	key.Usage.rollDay(now)
+	if key.DailyTokenBudget > 0 && key.Usage.DailyTokens >= key.DailyTokenBudget {
+		return nil, ErrAPIKeyBudgetExhausted
+	}
+	if key.RequestsPerMinute > 0 {
+		if now.Sub(key.minuteStart) >= time.Minute {
+			key.minuteStart = now
+			key.minuteCount = 0
+		}
+		if key.minuteCount >= key.RequestsPerMinute {
+			return nil, ErrAPIKeyRateLimited
+		}
+		key.minuteCount++
+	}
+
+	return &APIKeyIdentity{
+		ID:            key.ID,
+		Name:          key.Name,
+		AllowedModels: append([]string(nil), key.AllowedModels...),
+	}, nil
+}
+
+// RetryAfter 返回Key下一次可以请求的等待时间，用于 429 响应的 Retry-After
+func (r *APIKeyRegistry) RetryAfter(secret string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, exists := r.byHash[hashAPIKey(secret)]
+	if !exists {
+		return 0
+	}
+	now := time.Now()
+	if key.DailyTokenBudget > 0 && key.Usage.DailyTokens >= key.DailyTokenBudget {
+		year, month, day := now.Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()).Sub(now)
+	}
+	if wait := key.minuteStart.Add(time.Minute).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// RecordRequest 计入一次通过授权的请求
+func (r *APIKeyRegistry) RecordRequest(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, exists := r.keys[id]
+	if !exists {
+		return
+	}
+	now := time.Now()
+	key.Usage.rollDay(now)
+	key.Usage.TotalRequests++
+	key.Usage.DailyRequests++
+	key.Usage.LastUsed = now
+	r.dirty = true
+}
+
+// ReleaseRequest 退回 Authenticate 占用的每分钟请求数，用于处理函数拒绝的请求
+func (r *APIKeyRegistry) ReleaseRequest(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key, exists := r.keys[id]; exists && key.minuteCount > 0 {
+		key.minuteCount--
+	}
+}
+
+// RecordTokens 计入请求估算的token数
+func (r *APIKeyRegistry) RecordTokens(id string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, exists := r.keys[id]
+	if !exists {
+		return
+	}
+	key.Usage.rollDay(time.Now())
+	key.Usage.TotalTokens += int64(tokens)
+	key.Usage.DailyTokens += int64(tokens)
+	r.dirty = true
+}
+
+// saveLocked 写回文件，调用方需持有注册表锁
+func (r *APIKeyRegistry) saveLocked() error {
+	if r.path == "" {
+		return nil
+	}
+	keys := make([]*APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.path, data, 0o600); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
+// Save 写回未保存的用量
+func (r *APIKeyRegistry) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		return nil
+	}
+	return r.saveLocked()
+}
+
+// runFlush 定期写回用量
+func (r *APIKeyRegistry) runFlush() {
+	ticker := time.NewTicker(apiKeyFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Save(); err != nil {
+				logger.Error(fmt.Sprintf("Failed to save api key usage: %v", err))
+			}
+		case <-r.stopFlush:
+			return
+		}
+	}
+}
+
+// Close 停止定期写回并保存用量，在服务关闭时调用
+func (r *APIKeyRegistry) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stopFlush)
+	})
+	return r.Save()
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newFailingAPIKeyRegistry 返回含一个Key的注册表，之后的写回都会失败
+func newFailingAPIKeyRegistry(t *testing.T, spec APIKeySpec) (*APIKeyRegistry, *APIKey, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	registry, err := NewAPIKeyRegistry(path)
+	if err != nil {
+		t.Fatalf("NewAPIKeyRegistry: %v", err)
+	}
+	t.Cleanup(func() { registry.Close() })
+	key, secret, err := registry.Create(spec)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// 文件位置被目录占用，原子替换失败
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return registry, key, secret
+}
+
+func TestAPIKeyRegistryRollsBackUnsavedChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(r *APIKeyRegistry, id string) error
+	}{
+		{name: "create", change: func(r *APIKeyRegistry, id string) error {
+			_, _, err := r.Create(APIKeySpec{Name: "second"})
+			return err
+		}},
+		{name: "rotate", change: func(r *APIKeyRegistry, id string) error {
+			_, _, err := r.Rotate(id)
+			return err
+		}},
+		{name: "revoke", change: func(r *APIKeyRegistry, id string) error {
+			_, err := r.Revoke(id)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, key, secret := newFailingAPIKeyRegistry(t, APIKeySpec{Name: "first"})
+			if err := tt.change(registry, key.ID); err == nil {
+				t.Fatal("expected the save to fail")
+			}
+
+			if keys := registry.List(); len(keys) != 1 {
+				t.Fatalf("registry has %d keys, want 1", len(keys))
+			}
+			current, err := registry.Get(key.ID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if current.Status() != "active" || current.Prefix != key.Prefix || !current.RotatedAt.IsZero() {
+				t.Fatalf("key changed after failed save: %+v", current)
+			}
+			if _, err := registry.Authenticate(secret); err != nil {
+				t.Fatalf("original secret no longer authenticates: %v", err)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRegistryReleaseRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	registry, err := NewAPIKeyRegistry(path)
+	if err != nil {
+		t.Fatalf("NewAPIKeyRegistry: %v", err)
+	}
+	defer registry.Close()
+	key, secret, err := registry.Create(APIKeySpec{Name: "limited", RequestsPerMinute: 2})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 被拒绝的请求退回占用的额度，不影响后续请求
+	for i := 0; i < 5; i++ {
+		if _, err := registry.Authenticate(secret); err != nil {
+			t.Fatalf("Authenticate #%d: %v", i+1, err)
+		}
+		registry.ReleaseRequest(key.ID)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := registry.Authenticate(secret); err != nil {
+			t.Fatalf("Authenticate within quota: %v", err)
+		}
+	}
+	if _, err := registry.Authenticate(secret); !errors.Is(err, ErrAPIKeyRateLimited) {
+		t.Fatalf("Authenticate over quota error = %v, want %v", err, ErrAPIKeyRateLimited)
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"sync"
+)
+
+// UpstreamClient 连接池中缓存的上游客户端
+type UpstreamClient interface {
+	// Close 释放空闲连接
+	Close()
+}
+
+// ClientFactory 按 sessionKey 和代理创建上游客户端
+type ClientFactory func(sessionKey string, proxy string) UpstreamClient
+
+var (
+	clientFactory   ClientFactory
+	clientFactoryMu sync.RWMutex
+)
+
+// RegisterClientFactory 注册上游客户端的创建函数
+// config 包不能依赖 core，由 core 包在初始化时注册
+func RegisterClientFactory(factory ClientFactory) {
+	clientFactoryMu.Lock()
+	defer clientFactoryMu.Unlock()
+	clientFactory = factory
+}
+
+func getClientFactory() ClientFactory {
+	clientFactoryMu.RLock()
+	defer clientFactoryMu.RUnlock()
+	return clientFactory
+}
+
+// ClientPoolStats 连接池统计信息
+type ClientPoolStats struct {
+	Size      int   `json:"size"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Rebuilds  int64 `json:"rebuilds"`
+	Evictions int64 `json:"evictions"`
+}
+
+// clientPool 每个session一个长期复用的上游客户端，复用 TLS 连接与 HTTP/2 流
+type clientPool struct {
+	clients map[string]UpstreamClient
+	proxy   string
+	stats   ClientPoolStats
+	mu      sync.Mutex
+}
+
+func newClientPool() *clientPool {
+	return &clientPool{
+		clients: make(map[string]UpstreamClient),
+	}
+}
+
+// add 为session创建客户端，已存在时不做处理
+func (p *clientPool) add(sessionKey string) {
+	factory := getClientFactory()
+	if factory == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.clients[sessionKey]; exists {
+		return
+	}
+	p.clients[sessionKey] = factory(sessionKey, p.proxy)
+}
+
+// setProxy 设置代理并为sessions创建客户端，代理变化时重建已有客户端
+func (p *clientPool) setProxy(proxy string, sessionKeys []string) {
+	factory := getClientFactory()
+	if factory == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rebuildLocked(factory, proxy)
+	for _, key := range sessionKeys {
+		if _, exists := p.clients[key]; !exists {
+			p.clients[key] = factory(key, proxy)
+		}
+	}
+}
+
+// get 获取session的客户端，代理变化时重建全部客户端
+func (p *clientPool) get(sessionKey string, proxy string) UpstreamClient {
+	factory := getClientFactory()
+	if factory == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rebuildLocked(factory, proxy)
+
+	client, exists := p.clients[sessionKey]
+	if exists {
+		p.stats.Hits++
+		return client
+	}
+	p.stats.Misses++
+	client = factory(sessionKey, proxy)
+	p.clients[sessionKey] = client
+	return client
+}
+
+// rebuildLocked 代理变化时关闭旧客户端并按新代理重建
+func (p *clientPool) rebuildLocked(factory ClientFactory, proxy string) {
+	if proxy == p.proxy {
+		return
+	}
+	p.proxy = proxy
+	if len(p.clients) == 0 {
+		return
+	}
+	for key, client := range p.clients {
+		client.Close()
+		p.clients[key] = factory(key, proxy)
+	}
+	p.stats.Rebuilds++
+}
+
+// lookup 获取session已有的客户端，不存在时不创建
+func (p *clientPool) lookup(sessionKey string) UpstreamClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.clients[sessionKey]
+}
+
+// remove 关闭并移除session的客户端
+func (p *clientPool) remove(sessionKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, exists := p.clients[sessionKey]; exists {
+		client.Close()
+		delete(p.clients, sessionKey)
+		p.stats.Evictions++
+	}
+}
+
+func (p *clientPool) getStats() ClientPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Size = len(p.clients)
+	return stats
+}
@@ -45,9 +45,25 @@ func (e ErrorType) String() string {
 	}
 }
 
+// 思考内容（-think 模型）的输出方式
+const (
+	ThinkingModeInline    = "inline"            // 以 <think></think> 标签内联在正文中（旧版行为）
+	ThinkingModeReasoning = "reasoning_content" // 作为独立的 reasoning_content 字段输出
+	ThinkingModeDrop      = "drop"              // 丢弃思考内容
+)
+
 type SessionInfo struct {
-	SessionKey string `yaml:"sessionKey"`
-	OrgID      string `yaml:"orgID"`
+	SessionKey    string   `yaml:"sessionKey"`
+	OrgID         string   `yaml:"orgID"`
+	Tags          []string `yaml:"tags,omitempty"`          // session组标签，用于路由
+	Tier          string   `yaml:"tier,omitempty"`          // 账户层级，如 max、pro、free
+	AllowedModels []string `yaml:"allowedModels,omitempty"` // 可以服务的模型（支持通配），为空表示不限制
+	MaxConcurrent int      `yaml:"maxConcurrent,omitempty"` // 同时处理的请求数上限，0 表示使用 sessionManager.maxConcurrent
+}
+
+// SessionID 由sessionKey派生的稳定标识，管理接口返回脱敏的sessionKey时用它引用session
+func SessionID(sessionKey string) string {
+	return "ses_" + hashAPIKey(sessionKey)[:16]
 }
 
 type SessionRange struct {
@@ -58,12 +74,21 @@ type SessionRange struct {
 // SessionManagerConfig SessionManager配置
 type SessionManagerConfig struct {
 	Enabled                bool                   `yaml:"enabled"`
-	ScheduleStrategy       string                `yaml:"scheduleStrategy"` // "round_robin", "health_priority", "weighted"
+	ScheduleStrategy       string                `yaml:"scheduleStrategy"` // "round_robin", "health_priority", "weighted", "adaptive", "least_connections"
 	HealthCheckInterval    time.Duration         `yaml:"healthCheckInterval"`
 	MinHealthScore         float64               `yaml:"minHealthScore"`
 	CircuitBreakerEnabled  bool                  `yaml:"circuitBreakerEnabled"`
+	CircuitBreakerThreshold int                  `yaml:"circuitBreakerThreshold"` // 连续失败多少次后熔断，默认 5
+	CircuitBreakerTimeout  time.Duration         `yaml:"circuitBreakerTimeout"` // 熔断后多久进入半开状态，默认 60s
 	MaxRetryAttempts       int                   `yaml:"maxRetryAttempts"`
 	CooldownPeriods        map[string]time.Duration `yaml:"cooldownPeriods"`
+	QueueMaxWait           time.Duration         `yaml:"queueMaxWait"` // 所有session不可用时的最长排队时间，负数表示不排队
+	QueueMaxSize           int                   `yaml:"queueMaxSize"` // 最多同时排队的请求数
+	StateStore             StateStoreConfig      `yaml:"stateStore"` // 健康状态与统计的持久化
+	ProbeEnabled           bool                  `yaml:"probeEnabled"` // 按 healthCheckInterval 主动探测sessionKey是否有效
+	ProbeConcurrency       int                   `yaml:"probeConcurrency"` // 同时进行的探测数，默认 2
+	Routing                []RoutingRule         `yaml:"routing"` // 按模型把请求路由到session组，第一条匹配的规则生效
+	MaxConcurrent          int                   `yaml:"maxConcurrent"` // 每个session默认同时处理的请求数上限，0 表示不限制
 }
 
 type Config struct {
@@ -78,13 +103,39 @@ type Config struct {
     RetryCount             int                  `yaml:"retryCount"`
     NoRolePrefix           bool                 `yaml:"noRolePrefix"`
     PromptDisableArtifacts bool                 `yaml:"promptDisableArtifacts"`
+    ThinkingMode           string               `yaml:"thinkingMode"`
+    // 会话亲和：多轮对话复用同一个 claude.ai 会话，只发送新的用户轮次（需启用 sessionManager）
+    ConversationAffinity    bool                `yaml:"conversationAffinity"`
+    ConversationAffinityTTL time.Duration       `yaml:"conversationAffinityTTL"`
+    BufferFirstToken        bool                `yaml:"bufferFirstToken"` // 流式响应等到第一个增量再发送响应头，此前失败仍可换 session 重试
+    ConfigWatchInterval     time.Duration       `yaml:"configWatchInterval"` // 检查配置文件变化的间隔，负数表示不自动热加载
 	EnableMirrorApi        bool                 `yaml:"enableMirrorApi"`
 	MirrorApiPrefix        string               `yaml:"mirrorApiPrefix"`
-	AdminUser              string               `yaml:"adminUser"`
-	AdminPassword          string               `yaml:"adminPassword"`
+	AdminUser              string               `yaml:"adminUser"` // 首次启动时创建的 admin 账号
+	AdminPassword          string               `yaml:"adminPassword"` // 首个 admin 账号的初始密码，默认密码需在首次登录后修改
+	AdminUsersFile         string               `yaml:"adminUsersFile"` // 管理员账号文件，默认 data/admin_users.json
 	AdminSecret            string               `yaml:"adminSecret"`
+	AdminAccessTokenTTL    time.Duration        `yaml:"adminAccessTokenTTL"` // 管理端访问token有效期，默认 15m
+	AdminRefreshTokenTTL   time.Duration        `yaml:"adminRefreshTokenTTL"` // 刷新token有效期，每次刷新重新计算，默认 168h
+	AdminTokensFile        string               `yaml:"adminTokensFile"` // 刷新token与吊销列表文件，默认 data/admin_tokens.json
+	AdminAuth              string               `yaml:"adminAuth"` // 管理端认证方式：jwt_only（默认）、jwt_or_apikey 或 mtls
+	AdminAPITokensFile     string               `yaml:"adminAPITokensFile"` // 管理API token文件，默认 data/admin_api_tokens.json
+	TLS                    TLSConfig            `yaml:"tls"` // 配置证书后直接提供 HTTPS
+	APIKeysFile            string               `yaml:"apiKeysFile"` // 客户端API Key注册表文件，默认 data/api_keys.json
+	RateLimit              RateLimitConfig      `yaml:"rateLimit"` // 业务 API 的入站限流
+	TrustedProxies         []string             `yaml:"trustedProxies"` // 可信反向代理的 IP 或 CIDR，只信任来自它们的 X-Forwarded-For，默认不信任
 	RwMutx                 sync.RWMutex         `yaml:"-"` // 不从YAML加载
 	sessionManager         *SessionManager      `yaml:"-"` // SessionManager实例
+	configPath             string               `yaml:"-"` // 加载时使用的配置文件，为空表示来自环境变量
+	configHash             string               `yaml:"-"` // 最近一次加载或写入的配置文件内容哈希
+	apiKeyRegistry         *APIKeyRegistry      `yaml:"-"`
+	apiKeyRegistryOnce     sync.Once            `yaml:"-"`
+	adminUserStore         *AdminUserStore      `yaml:"-"`
+	adminUserStoreOnce     sync.Once            `yaml:"-"`
+	adminTokenStore        *AdminTokenStore     `yaml:"-"`
+	adminTokenStoreOnce    sync.Once            `yaml:"-"`
+	adminAPITokenStore     *AdminAPITokenStore  `yaml:"-"`
+	adminAPITokenStoreOnce sync.Once            `yaml:"-"`
 }
 
 // IsSessionManagerEnabled 检查SessionManager是否启用
@@ -92,6 +143,35 @@ func (c *Config) IsSessionManagerEnabled() bool {
 	return c.SessionManager.Enabled && len(c.Sessions) > 0
 }
 
+// GetThinkingMode 获取思考内容输出方式，requested 为单次请求指定的值，优先于全局配置
+func (c *Config) GetThinkingMode(requested string) string {
+	if IsValidThinkingMode(requested) {
+		return requested
+	}
+	if IsValidThinkingMode(c.ThinkingMode) {
+		return c.ThinkingMode
+	}
+	return ThinkingModeInline
+}
+
+// IsValidThinkingMode 检查思考输出方式是否合法
+func IsValidThinkingMode(mode string) bool {
+	switch mode {
+	case ThinkingModeInline, ThinkingModeReasoning, ThinkingModeDrop:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetConversationAffinityTTL 获取会话亲和记录的保留时间
+func (c *Config) GetConversationAffinityTTL() time.Duration {
+	if c.ConversationAffinityTTL <= 0 {
+		return 30 * time.Minute
+	}
+	return c.ConversationAffinityTTL
+}
+
 // GetAdminUser 获取管理员用户名
 func (c *Config) GetAdminUser() string {
 	if c.AdminUser == "" {
@@ -103,7 +183,7 @@ func (c *Config) GetAdminUser() string {
 // GetAdminPassword 获取管理员密码
 func (c *Config) GetAdminPassword() string {
 	if c.AdminPassword == "" {
-		return "admin123"
+		return DefaultAdminPassword
 	}
 	return c.AdminPassword
 }
@@ -116,36 +196,61 @@ func (c *Config) GetAdminSecret() string {
 	return c.AdminSecret
 }
 
+// GetAdminAccessTokenTTL 获取管理端访问token的有效期
+func (c *Config) GetAdminAccessTokenTTL() time.Duration {
+	if c.AdminAccessTokenTTL <= 0 {
+		return defaultAdminAccessTokenTTL
+	}
+	return c.AdminAccessTokenTTL
+}
+
+// GetAdminRefreshTokenTTL 获取管理端刷新token的有效期
+func (c *Config) GetAdminRefreshTokenTTL() time.Duration {
+	if c.AdminRefreshTokenTTL <= 0 {
+		return defaultAdminRefreshTokenTTL
+	}
+	return c.AdminRefreshTokenTTL
+}
+
 // ValidateConfig 验证配置
 func (c *Config) ValidateConfig() error {
 	if len(c.Sessions) == 0 {
 		return fmt.Errorf("no sessions configured")
 	}
 	
+	errs := &ValidationError{}
 	for i, session := range c.Sessions {
 		if session.SessionKey == "" {
 			return fmt.Errorf("session %d has empty session key", i+1)
 		}
+		session.validate(errs, fmt.Sprintf("sessions[%d].", i))
+	}
+	if err := errs.Err(); err != nil {
+		return err
 	}
 	
 	if c.APIKey == "" {
 		return fmt.Errorf("API key is required")
 	}
+	if c.ThinkingMode != "" && !IsValidThinkingMode(c.ThinkingMode) {
+		return fmt.Errorf("invalid thinking mode: %s", c.ThinkingMode)
+	}
+	if err := c.RateLimit.Validate(); err != nil {
+		return err
+	}
+	if err := validateTrustedProxies(c.TrustedProxies); err != nil {
+		return err
+	}
+	if c.GetAdminRefreshTokenTTL() < c.GetAdminAccessTokenTTL() {
+		return fmt.Errorf("adminRefreshTokenTTL must not be shorter than adminAccessTokenTTL")
+	}
+	if err := c.validateAdminAuth(); err != nil {
+		return err
+	}
 	
 	if c.SessionManager.Enabled {
-		if c.SessionManager.ScheduleStrategy != "round_robin" && 
-		   c.SessionManager.ScheduleStrategy != "health_priority" && 
-		   c.SessionManager.ScheduleStrategy != "weighted" &&
-		   c.SessionManager.ScheduleStrategy != "adaptive" {
-			return fmt.Errorf("invalid session manager strategy: %s", c.SessionManager.ScheduleStrategy)
-		}
-		
-		if c.SessionManager.MinHealthScore < 0 || c.SessionManager.MinHealthScore > 1 {
-			return fmt.Errorf("min health score must be between 0 and 1")
-		}
-		
-		if c.SessionManager.MaxRetryAttempts < 0 {
-			return fmt.Errorf("max retry attempts must be non-negative")
+		if err := c.SessionManager.Validate(); err != nil {
+			return err
 		}
 	}
 	
@@ -156,10 +261,94 @@ func (c *Config) ValidateConfig() error {
 func (c *Config) GetSessionManager() *SessionManager {
 	if c.sessionManager == nil && c.IsSessionManagerEnabled() {
 		c.sessionManager = NewSessionManager(c.Sessions, c.SessionManager)
+		c.sessionManager.onOrgIDChanged = c.SetSessionOrgID
+		c.sessionManager.SetClientProxy(c.Proxy)
 	}
 	return c.sessionManager
 }
 
+// GetAPIKeyRegistry 获取客户端API Key注册表，首次调用时从 apiKeysFile 加载
+// 文件无法读取时使用不写回文件的空注册表，避免覆盖原文件
+func (c *Config) GetAPIKeyRegistry() *APIKeyRegistry {
+	c.apiKeyRegistryOnce.Do(func() {
+		path := c.APIKeysFile
+		if path == "" {
+			path = defaultAPIKeysFile
+		}
+		registry, err := NewAPIKeyRegistry(path)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to load api keys, registry changes will not be saved: %v", err))
+			registry, _ = NewAPIKeyRegistry("")
+		}
+		c.apiKeyRegistry = registry
+	})
+	return c.apiKeyRegistry
+}
+
+// GetAdminUserStore 获取管理员账号存储，首次调用时从 adminUsersFile 加载，
+// 文件中没有账号时用 adminUser/adminPassword 创建首个 admin 账号
+// 文件无法读取时使用不写回文件的存储，避免覆盖原文件
+func (c *Config) GetAdminUserStore() *AdminUserStore {
+	c.adminUserStoreOnce.Do(func() {
+		path := c.AdminUsersFile
+		if path == "" {
+			path = defaultAdminUsersFile
+		}
+		store, err := NewAdminUserStore(path, c.GetAdminUser(), c.GetAdminPassword())
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to load admin users, account changes will not be saved: %v", err))
+			store, err = NewAdminUserStore("", c.GetAdminUser(), c.GetAdminPassword())
+			if err != nil {
+				logger.Fatal(fmt.Sprintf("Failed to create admin user store: %v", err))
+			}
+		}
+		c.adminUserStore = store
+		// 提示仍需修改初始密码的账号
+		for _, user := range store.List() {
+			if user.MustChangePassword {
+				logger.Warn(fmt.Sprintf("Admin user %q must change its password before the admin API unlocks", user.Username))
+			}
+		}
+	})
+	return c.adminUserStore
+}
+
+// GetAdminTokenStore 获取管理端的刷新token与吊销列表，首次调用时从 adminTokensFile 加载
+// 文件无法读取时使用不写回文件的存储，避免覆盖原文件
+func (c *Config) GetAdminTokenStore() *AdminTokenStore {
+	c.adminTokenStoreOnce.Do(func() {
+		path := c.AdminTokensFile
+		if path == "" {
+			path = defaultAdminTokensFile
+		}
+		store, err := NewAdminTokenStore(path)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to load admin tokens, revocations will not be saved: %v", err))
+			store, _ = NewAdminTokenStore("")
+		}
+		c.adminTokenStore = store
+	})
+	return c.adminTokenStore
+}
+
+// GetAdminAPITokenStore 获取管理API token存储，首次调用时从 adminAPITokensFile 加载
+// 文件无法读取时使用不写回文件的空存储，避免覆盖原文件
+func (c *Config) GetAdminAPITokenStore() *AdminAPITokenStore {
+	c.adminAPITokenStoreOnce.Do(func() {
+		path := c.AdminAPITokensFile
+		if path == "" {
+			path = defaultAdminAPITokensFile
+		}
+		store, err := NewAdminAPITokenStore(path)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to load admin api tokens, token changes will not be saved: %v", err))
+			store, _ = NewAdminAPITokenStore("")
+		}
+		c.adminAPITokenStore = store
+	})
+	return c.adminAPITokenStore
+}
+
 // 解析 SESSION 格式的环境变量
 func parseSessionEnv(envValue string) (int, []SessionInfo) {
 	if envValue == "" {
@@ -262,6 +451,7 @@ func loadConfigFromYAML(configPath string) (*Config, error) {
 
     // 设置读写锁（不从YAML加载）
     config.RwMutx = sync.RWMutex{}
+    config.configHash = contentHash(data)
 
     // 如果地址为空，使用默认值
     if config.Address == "" {
@@ -306,6 +496,18 @@ func loadConfigFromEnv() *Config {
 	if err != nil {
 		maxRetryAttempts = 3
 	}
+	circuitBreakerThreshold, _ := strconv.Atoi(os.Getenv("CIRCUIT_BREAKER_THRESHOLD"))
+	circuitBreakerTimeout, _ := time.ParseDuration(os.Getenv("CIRCUIT_BREAKER_TIMEOUT"))
+	queueMaxWait, _ := time.ParseDuration(os.Getenv("QUEUE_MAX_WAIT"))
+	queueMaxSize, _ := strconv.Atoi(os.Getenv("QUEUE_MAX_SIZE"))
+	stateSnapshotInterval, _ := time.ParseDuration(os.Getenv("STATE_SNAPSHOT_INTERVAL"))
+	probeConcurrency, _ := strconv.Atoi(os.Getenv("SESSION_PROBE_CONCURRENCY"))
+	maxConcurrent, _ := strconv.Atoi(os.Getenv("SESSION_MAX_CONCURRENT"))
+	rateLimitRequests, _ := strconv.Atoi(os.Getenv("RATE_LIMIT_REQUESTS"))
+	rateLimitTokens, _ := strconv.Atoi(os.Getenv("RATE_LIMIT_TOKENS"))
+	rateLimitWindow, _ := time.ParseDuration(os.Getenv("RATE_LIMIT_WINDOW"))
+	adminAccessTokenTTL, _ := time.ParseDuration(os.Getenv("ADMIN_ACCESS_TOKEN_TTL"))
+	adminRefreshTokenTTL, _ := time.ParseDuration(os.Getenv("ADMIN_REFRESH_TOKEN_TTL"))
 	
     config := &Config{
         // 解析 SESSIONS 环境变量
@@ -317,8 +519,20 @@ func loadConfigFromEnv() *Config {
 			HealthCheckInterval:    healthCheckInterval,
 			MinHealthScore:         minHealthScore,
 			CircuitBreakerEnabled:  circuitBreakerEnabled,
+			CircuitBreakerThreshold: circuitBreakerThreshold,
+			CircuitBreakerTimeout:  circuitBreakerTimeout,
 			MaxRetryAttempts:       maxRetryAttempts,
 			CooldownPeriods:        getDefaultCooldownPeriods(),
+			QueueMaxWait:           queueMaxWait,
+			QueueMaxSize:           queueMaxSize,
+			StateStore: StateStoreConfig{
+				Type:             os.Getenv("STATE_STORE"),
+				Path:             os.Getenv("STATE_FILE"),
+				SnapshotInterval: stateSnapshotInterval,
+			},
+			ProbeEnabled:           os.Getenv("SESSION_PROBE_ENABLED") == "true",
+			ProbeConcurrency:       probeConcurrency,
+			MaxConcurrent:          maxConcurrent,
 		},
         // 设置服务地址，默认为 "0.0.0.0:8080"
         Address: os.Getenv("ADDRESS"),
@@ -356,6 +570,16 @@ func loadConfigFromEnv() *Config {
 		NoRolePrefix: os.Getenv("NO_ROLE_PREFIX") == "true",
 		// 设置是否使用提示词禁用artifacts
 		PromptDisableArtifacts: os.Getenv("PROMPT_DISABLE_ARTIFACTS") == "true",
+		// 设置思考内容输出方式
+		ThinkingMode: os.Getenv("THINKING_MODE"),
+		// 设置会话亲和
+		ConversationAffinity: os.Getenv("CONVERSATION_AFFINITY") == "true",
+		ConversationAffinityTTL: func() time.Duration {
+			ttl, _ := time.ParseDuration(os.Getenv("CONVERSATION_AFFINITY_TTL"))
+			return ttl
+		}(),
+		// 设置流式响应是否等待第一个增量
+		BufferFirstToken: os.Getenv("BUFFER_FIRST_TOKEN") == "true",
 		// 设置是否启用镜像API
 		EnableMirrorApi: os.Getenv("ENABLE_MIRROR_API") == "true",
 		// 设置镜像API前缀
@@ -364,6 +588,37 @@ func loadConfigFromEnv() *Config {
 		AdminUser: os.Getenv("ADMIN_USER"),
 		AdminPassword: os.Getenv("ADMIN_PASSWORD"),
 		AdminSecret: os.Getenv("ADMIN_SECRET"),
+		AdminAccessTokenTTL: adminAccessTokenTTL,
+		AdminRefreshTokenTTL: adminRefreshTokenTTL,
+		AdminTokensFile: os.Getenv("ADMIN_TOKENS_FILE"),
+		AdminAuth: os.Getenv("ADMIN_AUTH"),
+		AdminAPITokensFile: os.Getenv("ADMIN_API_TOKENS_FILE"),
+		TLS: TLSConfig{
+			CertFile: os.Getenv("TLS_CERT_FILE"),
+			KeyFile: os.Getenv("TLS_KEY_FILE"),
+			ClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+		},
+		AdminUsersFile: os.Getenv("ADMIN_USERS_FILE"),
+		// 设置客户端API Key注册表文件
+		APIKeysFile: os.Getenv("API_KEYS_FILE"),
+		// 设置入站限流
+		RateLimit: RateLimitConfig{
+			Enabled:  os.Getenv("RATE_LIMIT_ENABLED") == "true",
+			KeyBy:    os.Getenv("RATE_LIMIT_KEY_BY"),
+			Requests: rateLimitRequests,
+			Tokens:   rateLimitTokens,
+			Window:   rateLimitWindow,
+		},
+		// 可信反向代理，逗号分隔
+		TrustedProxies: func() []string {
+			var proxies []string
+			for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+				if s := strings.TrimSpace(p); s != "" {
+					proxies = append(proxies, s)
+				}
+			}
+			return proxies
+		}(),
 		// 设置读写锁
 		RwMutx: sync.RWMutex{},
 	}
@@ -384,6 +639,7 @@ func LoadConfig() *Config {
 		config, err := loadConfigFromYAML(configPath)
 		if err == nil {
 			logger.Info("Successfully loaded configuration from YAML file")
+			config.configPath = configPath
 			return config
 		}
 		logger.Error(fmt.Sprintf("Failed to load config from YAML: %v, falling back to environment variables", err))
@@ -391,7 +647,14 @@ func LoadConfig() *Config {
 
 	// 如果配置文件不存在或加载失败，从环境变量加载
 	logger.Info("Loading configuration from environment variables")
-	return loadConfigFromEnv()
+	config := loadConfigFromEnv()
+	// 管理界面的修改保存在单独的文件中，优先于环境变量
+	if err := config.loadRuntimeConfig(); err != nil {
+		logger.Error(fmt.Sprintf("Failed to load runtime config: %v", err))
+	} else if _, err := os.Stat(config.PersistPath()); err == nil {
+		logger.Info(fmt.Sprintf("Applied runtime config from %s", config.PersistPath()))
+	}
+	return config
 }
 
 var ConfigInstance *Config
@@ -399,20 +662,24 @@ var Sr *SessionRange
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
-	// 加载环境变量
-	_ = godotenv.Load()
 	Sr = &SessionRange{
 		Index: 0,
 		Mutex: sync.Mutex{},
 	}
-	ConfigInstance = LoadConfig()
-	
+}
+
+// Init 加载环境变量与配置文件，校验通过后写入 ConfigInstance，由 main 在启动时调用
+func Init() error {
+	// 加载环境变量
+	_ = godotenv.Load()
+	config := LoadConfig()
+
 	// 验证配置
-	if err := ConfigInstance.ValidateConfig(); err != nil {
-		logger.Error(fmt.Sprintf("Configuration validation failed: %v", err))
-		os.Exit(1)
+	if err := config.ValidateConfig(); err != nil {
+		return err
 	}
-	
+	ConfigInstance = config
+
     logger.Info("Loaded config:")
     logger.Info(fmt.Sprintf("Max Retry count: %d", ConfigInstance.RetryCount))
     for _, session := range ConfigInstance.Sessions {
@@ -430,16 +697,19 @@ func init() {
     logger.Info(fmt.Sprintf("MaxChatHistoryLength: %d", ConfigInstance.MaxChatHistoryLength))
     logger.Info(fmt.Sprintf("NoRolePrefix: %t", ConfigInstance.NoRolePrefix))
     logger.Info(fmt.Sprintf("PromptDisableArtifacts: %t", ConfigInstance.PromptDisableArtifacts))
+    logger.Info(fmt.Sprintf("ThinkingMode: %s", ConfigInstance.GetThinkingMode("")))
+    logger.Info(fmt.Sprintf("ConversationAffinity: %t (ttl %v)", ConfigInstance.ConversationAffinity, ConfigInstance.GetConversationAffinityTTL()))
+    logger.Info(fmt.Sprintf("BufferFirstToken: %t", ConfigInstance.BufferFirstToken))
     logger.Info(fmt.Sprintf("EnableMirrorApi: %t", ConfigInstance.EnableMirrorApi))
     logger.Info(fmt.Sprintf("MirrorApiPrefix: %s", ConfigInstance.MirrorApiPrefix))
     logger.Info(fmt.Sprintf("CORS Allowed Origins: %v", ConfigInstance.CORSAllowedOrigins))
+    logger.Info(fmt.Sprintf("Trusted Proxies: %v", ConfigInstance.TrustedProxies))
+    logger.Info(fmt.Sprintf("AdminAuth: %s", ConfigInstance.GetAdminAuth()))
+    ConfigInstance.warnAdminAuth()
     logger.Info(fmt.Sprintf("SessionManager Enabled: %t", ConfigInstance.SessionManager.Enabled))
     logger.Info(fmt.Sprintf("SessionManager Strategy: %s", ConfigInstance.SessionManager.ScheduleStrategy))
     logger.Info(fmt.Sprintf("SessionManager MinHealthScore: %f", ConfigInstance.SessionManager.MinHealthScore))
     logger.Info(fmt.Sprintf("SessionManager MaxRetryAttempts: %d", ConfigInstance.SessionManager.MaxRetryAttempts))
-
-    // 提示默认管理员凭据风险
-    if ConfigInstance.GetAdminUser() == "admin" && ConfigInstance.GetAdminPassword() == "admin123" {
-        logger.Warn("Admin credentials are default values; please change in production")
-    }
+    logger.Info(fmt.Sprintf("SessionManager QueueMaxWait: %v, QueueMaxSize: %d", ConfigInstance.SessionManager.QueueMaxWait, ConfigInstance.SessionManager.QueueMaxSize))
+    return nil
 }
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// 配置来自环境变量时，运行时修改保存到单独的文件，启动时覆盖环境变量中的值
+	defaultRuntimeConfigPath = "data/runtime_config.yaml"
+	// 每个配置文件最多保留的备份数
+	maxConfigBackups = 5
+)
+
+// persistMu 串行化配置文件的写入
+var persistMu sync.Mutex
+
+// runtimeConfig 可以在管理界面修改、需要写回磁盘的配置
+type runtimeConfig struct {
+	Sessions               []SessionInfo               `yaml:"sessions"`
+	SessionManager         runtimeSessionManagerConfig `yaml:"sessionManager"`
+	ChatDelete             bool                        `yaml:"chatDelete"`
+	MaxChatHistoryLength   int                         `yaml:"maxChatHistoryLength"`
+	RetryCount             int                         `yaml:"retryCount"`
+	NoRolePrefix           bool                        `yaml:"noRolePrefix"`
+	PromptDisableArtifacts bool                        `yaml:"promptDisableArtifacts"`
+	ThinkingMode           string                      `yaml:"thinkingMode,omitempty"`
+	EnableMirrorApi        bool                        `yaml:"enableMirrorApi"`
+	MirrorApiPrefix        string                      `yaml:"mirrorApiPrefix"`
+}
+
+type runtimeSessionManagerConfig struct {
+	Enabled                 bool                     `yaml:"enabled"`
+	ScheduleStrategy        string                   `yaml:"scheduleStrategy"`
+	HealthCheckInterval     time.Duration            `yaml:"healthCheckInterval"`
+	MinHealthScore          float64                  `yaml:"minHealthScore"`
+	CircuitBreakerEnabled   bool                     `yaml:"circuitBreakerEnabled"`
+	CircuitBreakerThreshold int                      `yaml:"circuitBreakerThreshold,omitempty"`
+	CircuitBreakerTimeout   time.Duration            `yaml:"circuitBreakerTimeout,omitempty"`
+	MaxRetryAttempts        int                      `yaml:"maxRetryAttempts"`
+	CooldownPeriods         map[string]time.Duration `yaml:"cooldownPeriods,omitempty"`
+	QueueMaxWait            time.Duration            `yaml:"queueMaxWait,omitempty"`
+	QueueMaxSize            int                      `yaml:"queueMaxSize,omitempty"`
+	ProbeEnabled            bool                     `yaml:"probeEnabled"`
+	ProbeConcurrency        int                      `yaml:"probeConcurrency,omitempty"`
+	Routing                 []RoutingRule            `yaml:"routing,omitempty"`
+	MaxConcurrent           int                      `yaml:"maxConcurrent,omitempty"`
+}
+
+// runtimeSnapshot 复制当前的运行时配置，调用方需持有读锁
+func (c *Config) runtimeSnapshot() *runtimeConfig {
+	sessions := make([]SessionInfo, len(c.Sessions))
+	copy(sessions, c.Sessions)
+	return &runtimeConfig{
+		Sessions: sessions,
+		SessionManager: runtimeSessionManagerConfig{
+			Enabled:                 c.SessionManager.Enabled,
+			ScheduleStrategy:        c.SessionManager.ScheduleStrategy,
+			HealthCheckInterval:     c.SessionManager.HealthCheckInterval,
+			MinHealthScore:          c.SessionManager.MinHealthScore,
+			CircuitBreakerEnabled:   c.SessionManager.CircuitBreakerEnabled,
+			CircuitBreakerThreshold: c.SessionManager.CircuitBreakerThreshold,
+			CircuitBreakerTimeout:   c.SessionManager.CircuitBreakerTimeout,
+			MaxRetryAttempts:        c.SessionManager.MaxRetryAttempts,
+			CooldownPeriods:         c.SessionManager.CooldownPeriods,
+			QueueMaxWait:            c.SessionManager.QueueMaxWait,
+			QueueMaxSize:            c.SessionManager.QueueMaxSize,
+			ProbeEnabled:            c.SessionManager.ProbeEnabled,
+			ProbeConcurrency:        c.SessionManager.ProbeConcurrency,
+			Routing:                 c.SessionManager.Routing,
+			MaxConcurrent:           c.SessionManager.MaxConcurrent,
+		},
+		ChatDelete:             c.ChatDelete,
+		MaxChatHistoryLength:   c.MaxChatHistoryLength,
+		RetryCount:             c.RetryCount,
+		NoRolePrefix:           c.NoRolePrefix,
+		PromptDisableArtifacts: c.PromptDisableArtifacts,
+		ThinkingMode:           c.ThinkingMode,
+		EnableMirrorApi:        c.EnableMirrorApi,
+		MirrorApiPrefix:        c.MirrorApiPrefix,
+	}
+}
+
+// PersistPath 返回运行时修改写入的文件：加载时使用的 config.yaml，或环境变量模式下的运行时配置文件
+func (c *Config) PersistPath() string {
+	if c.configPath != "" {
+		return c.configPath
+	}
+	if path := os.Getenv("RUNTIME_CONFIG_FILE"); path != "" {
+		return path
+	}
+	return defaultRuntimeConfigPath
+}
+
+// Persist 把sessions列表和运行时可修改的配置写回磁盘
+// 写入前备份原文件，先写临时文件再重命名；写回 config.yaml 时保留其余字段与注释
+// 调用方不能持有 RwMutx
+func (c *Config) Persist() error {
+	c.RwMutx.RLock()
+	snapshot := c.runtimeSnapshot()
+	c.RwMutx.RUnlock()
+
+	persistMu.Lock()
+	defer persistMu.Unlock()
+
+	path := c.PersistPath()
+	original, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	data, err := mergeRuntimeConfig(original, snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	if bytes.Equal(data, original) {
+		return nil
+	}
+
+	perm := secretFileMode(path)
+	if original != nil {
+		if err := backupConfigFile(path, original, perm); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+	if err := writeFileAtomic(path, data, perm); err != nil {
+		return err
+	}
+	c.RwMutx.Lock()
+	c.configHash = contentHash(data)
+	c.RwMutx.Unlock()
+	return nil
+}
+
+// mergeRuntimeConfig 把运行时配置合并进已有的 YAML 文档，原文档为空时直接生成
+func mergeRuntimeConfig(original []byte, snapshot *runtimeConfig) ([]byte, error) {
+	var update yaml.Node
+	if err := update.Encode(snapshot); err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(original, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&update}}
+	} else {
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			return nil, errors.New("top level of config file is not a mapping")
+		}
+		mergeMapping(doc.Content[0], &update)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeMapping 用 src 中的键覆盖 dst，dst 中其余的键、顺序与注释保持不变
+func mergeMapping(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		existing := mappingValue(dst, key.Value)
+		switch {
+		case existing == nil:
+			dst.Content = append(dst.Content, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeMapping(existing, value)
+		default:
+			replaceNode(existing, value)
+		}
+	}
+}
+
+// mappingValue 查找映射中指定键的值节点
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// replaceNode 原地替换节点内容，保留原节点的注释和标量的引号风格
+func replaceNode(dst, src *yaml.Node) {
+	head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+	style := dst.Style
+	sameScalar := dst.Kind == yaml.ScalarNode && src.Kind == yaml.ScalarNode && dst.ShortTag() == src.ShortTag()
+
+	*dst = *src
+	dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+	if sameScalar {
+		dst.Style = style
+	}
+}
+
+// backupConfigFile 写入带时间戳的备份，只保留最近 maxConfigBackups 个
+// 时间戳精确到纳秒且定长，按文件名排序即按时间排序；同一秒内的多次保存不会相互覆盖
+func backupConfigFile(path string, data []byte, perm os.FileMode) error {
+	backup := fmt.Sprintf("%s.bak.%s", path, time.Now().Format("20060102-150405.000000000"))
+	for seq := 1; ; seq++ {
+		if _, err := os.Stat(backup); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		backup = fmt.Sprintf("%s.bak.%s-%d", path, time.Now().Format("20060102-150405.000000000"), seq)
+	}
+	if err := writeFileAtomic(backup, data, perm); err != nil {
+		return err
+	}
+
+	backups, err := filepath.Glob(path + ".bak.*")
+	if err != nil {
+		return nil
+	}
+	sort.Strings(backups)
+	for len(backups) > maxConfigBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// loadRuntimeConfig 环境变量模式下读取运行时配置文件，覆盖环境变量中的对应值
+func (c *Config) loadRuntimeConfig() error {
+	path := c.PersistPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// secretFileMode 返回保存含sessionKey等凭据的文件时使用的权限：默认 0600，
+// 已有文件的权限更严格时沿用
+func secretFileMode(path string) os.FileMode {
+	perm := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&^perm == 0 {
+		perm = info.Mode().Perm()
+	}
+	return perm
+}
+
+// writeFileAtomic 先写同目录下的临时文件再重命名，避免进程中断时留下不完整的文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
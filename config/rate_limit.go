@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// 限流的计数维度
+const (
+	RateLimitKeyAPIKey   = "api_key"    // 按 API Key，未携带 Key 的请求按客户端 IP
+	RateLimitKeyIP       = "ip"         // 按客户端 IP
+	RateLimitKeyAPIKeyIP = "api_key_ip" // 按 API Key 与客户端 IP 的组合
+)
+
+const defaultRateLimitWindow = time.Minute
+
+// RateLimitConfig 业务 API 的入站限流配置，使用令牌桶：每个窗口补满一次，允许短时突发
+type RateLimitConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	KeyBy    string        `yaml:"keyBy"`    // api_key、ip 或 api_key_ip，默认 api_key
+	Requests int           `yaml:"requests"` // 每个窗口的请求数，0 表示不限制
+	Tokens   int           `yaml:"tokens"`   // 每个窗口的估算 token 数，0 表示不限制
+	Window   time.Duration `yaml:"window"`   // 窗口长度，默认 1m
+}
+
+// WithDefaults 返回填充默认值后的配置
+func (cfg RateLimitConfig) WithDefaults() RateLimitConfig {
+	if cfg.KeyBy == "" {
+		cfg.KeyBy = RateLimitKeyAPIKey
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultRateLimitWindow
+	}
+	return cfg
+}
+
+// Validate 校验限流配置，零值表示使用默认值
+func (cfg RateLimitConfig) Validate() error {
+	errs := &ValidationError{}
+	switch cfg.KeyBy {
+	case "", RateLimitKeyAPIKey, RateLimitKeyIP, RateLimitKeyAPIKeyIP:
+	default:
+		errs.Add("rateLimit.keyBy", "must be one of %s, %s, %s", RateLimitKeyAPIKey, RateLimitKeyIP, RateLimitKeyAPIKeyIP)
+	}
+	if cfg.Requests < 0 {
+		errs.Add("rateLimit.requests", "must be non-negative")
+	}
+	if cfg.Tokens < 0 {
+		errs.Add("rateLimit.tokens", "must be non-negative")
+	}
+	if cfg.Window < 0 {
+		errs.Add("rateLimit.window", "must be non-negative")
+	}
+	return errs.Err()
+}
+
+// validateTrustedProxies 可信代理必须是 IP 或 CIDR
+func validateTrustedProxies(proxies []string) error {
+	errs := &ValidationError{}
+	for i, proxy := range proxies {
+		if strings.Contains(proxy, "/") {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs.Add(fmt.Sprintf("trustedProxies[%d]", i), "invalid CIDR %q", proxy)
+			}
+		} else if net.ParseIP(proxy) == nil {
+			errs.Add(fmt.Sprintf("trustedProxies[%d]", i), "invalid IP address %q", proxy)
+		}
+	}
+	return errs.Err()
+}
+
+// GetRateLimit 获取填充默认值后的限流配置
+func (c *Config) GetRateLimit() RateLimitConfig {
+	c.RwMutx.RLock()
+	defer c.RwMutx.RUnlock()
+	return c.RateLimit.WithDefaults()
+}
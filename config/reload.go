@@ -0,0 +1,279 @@
+package config
+
+import (
+	"claude2api/logger"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrReloadUnavailable 配置来自环境变量，没有可以重新加载的配置文件
+var ErrReloadUnavailable = errors.New("configuration was loaded from environment variables, nothing to reload")
+
+const defaultConfigWatchInterval = 2 * time.Second
+
+// ReloadResult 一次热加载的结果
+type ReloadResult struct {
+	Path            string    `json:"path"`
+	ReloadedAt      time.Time `json:"reloaded_at"`
+	SessionsAdded   []string  `json:"sessions_added"`
+	SessionsRemoved []string  `json:"sessions_removed"`
+	SessionsUpdated []string  `json:"sessions_updated"`
+	// Changed 已生效的配置项
+	Changed []string `json:"changed"`
+	// RestartRequired 已修改但需要重启服务才能生效的配置项
+	RestartRequired []string `json:"restart_required"`
+}
+
+var (
+	reloadHooks   []func(*ReloadResult)
+	reloadHooksMu sync.RWMutex
+)
+
+// OnReload 注册热加载完成后的回调，用于通知其他模块（如 WebSocket 广播）
+func OnReload(hook func(*ReloadResult)) {
+	reloadHooksMu.Lock()
+	defer reloadHooksMu.Unlock()
+	reloadHooks = append(reloadHooks, hook)
+}
+
+// contentHash 计算配置文件内容的哈希，用于判断文件是否被外部修改
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Reload 重新读取配置文件，校验通过后与当前配置对比并应用差异
+// 新增和移除的session同步到SessionManager，未变化的session保留健康状态；校验失败时保持当前配置不变
+func (c *Config) Reload() (*ReloadResult, error) {
+	if c.configPath == "" {
+		return nil, ErrReloadUnavailable
+	}
+
+	// 与 Persist 互斥，避免读到写了一半的文件
+	persistMu.Lock()
+	defer persistMu.Unlock()
+
+	next, err := loadConfigFromYAML(c.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := next.ValidateConfig(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+	if err := c.validateAdminAuthReload(next.AdminAuth); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	result := &ReloadResult{
+		Path:            c.configPath,
+		ReloadedAt:      time.Now(),
+		SessionsAdded:   []string{},
+		SessionsRemoved: []string{},
+		SessionsUpdated: []string{},
+		Changed:         []string{},
+		RestartRequired: []string{},
+	}
+
+	c.RwMutx.Lock()
+	oldProxy := c.Proxy
+	c.applyReloaded(next, result)
+	sessions := make([]SessionInfo, len(c.Sessions))
+	copy(sessions, c.Sessions)
+	managerConfig := c.SessionManager
+	proxy := c.Proxy
+	c.configHash = next.configHash
+	c.RwMutx.Unlock()
+
+	// 同步到SessionManager，已停用时也同步，重新启用后与配置保持一致
+	sm := c.sessionManager
+	if sm == nil {
+		sm = c.GetSessionManager()
+	} else {
+		added, removed := sm.SyncSessions(sessions)
+		result.SessionsAdded = append(result.SessionsAdded, added...)
+		result.SessionsRemoved = append(result.SessionsRemoved, removed...)
+		if err := sm.ApplyConfig(managerConfig); err != nil {
+			logger.Error(fmt.Sprintf("Failed to apply session manager config: %v", err))
+		}
+		if proxy != oldProxy {
+			sm.SetClientProxy(proxy)
+		}
+	}
+
+	logger.Info(fmt.Sprintf("Reloaded config from %s: %d sessions added, %d removed, %d updated, changed %v",
+		c.configPath, len(result.SessionsAdded), len(result.SessionsRemoved), len(result.SessionsUpdated), result.Changed))
+	if len(result.RestartRequired) > 0 {
+		logger.Warn(fmt.Sprintf("Config changes require a restart to take effect: %s", strings.Join(result.RestartRequired, ", ")))
+	}
+	for _, name := range result.Changed {
+		if name == "adminAuth" {
+			c.warnAdminAuth()
+		}
+	}
+
+	reloadHooksMu.RLock()
+	hooks := append([]func(*ReloadResult){}, reloadHooks...)
+	reloadHooksMu.RUnlock()
+	for _, hook := range hooks {
+		hook(result)
+	}
+	return result, nil
+}
+
+// applyReloaded 把新配置中可以在线生效的字段写入当前配置，调用方需持有写锁
+func (c *Config) applyReloaded(next *Config, result *ReloadResult) {
+	// sessions：SessionManager 不存在时在这里统计差异，否则以 SyncSessions 的结果为准
+	previous := make(map[string]SessionInfo, len(c.Sessions))
+	for _, session := range c.Sessions {
+		previous[session.SessionKey] = session
+	}
+	current := make(map[string]bool, len(next.Sessions))
+	for i, session := range next.Sessions {
+		current[session.SessionKey] = true
+		old, exists := previous[session.SessionKey]
+		switch {
+		case !exists:
+			if c.sessionManager == nil {
+				result.SessionsAdded = append(result.SessionsAdded, session.SessionKey)
+			}
+		default:
+			// 保留运行中自动获取的 orgID
+			if session.OrgID == "" {
+				next.Sessions[i].OrgID = old.OrgID
+			}
+			if !reflect.DeepEqual(next.Sessions[i], old) {
+				result.SessionsUpdated = append(result.SessionsUpdated, session.SessionKey)
+			}
+		}
+	}
+	if c.sessionManager == nil {
+		for key := range previous {
+			if !current[key] {
+				result.SessionsRemoved = append(result.SessionsRemoved, key)
+			}
+		}
+	}
+	c.Sessions = next.Sessions
+
+	live := func(name string, dst, src interface{}) {
+		target := reflect.ValueOf(dst).Elem()
+		value := reflect.ValueOf(src)
+		if !reflect.DeepEqual(target.Interface(), value.Interface()) {
+			target.Set(value)
+			result.Changed = append(result.Changed, name)
+		}
+	}
+	restart := func(name string, current, next interface{}) {
+		if !reflect.DeepEqual(current, next) {
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+	}
+
+	live("sessionManager.enabled", &c.SessionManager.Enabled, next.SessionManager.Enabled)
+	live("sessionManager.scheduleStrategy", &c.SessionManager.ScheduleStrategy, next.SessionManager.ScheduleStrategy)
+	live("sessionManager.healthCheckInterval", &c.SessionManager.HealthCheckInterval, next.SessionManager.HealthCheckInterval)
+	live("sessionManager.minHealthScore", &c.SessionManager.MinHealthScore, next.SessionManager.MinHealthScore)
+	live("sessionManager.circuitBreakerEnabled", &c.SessionManager.CircuitBreakerEnabled, next.SessionManager.CircuitBreakerEnabled)
+	live("sessionManager.circuitBreakerThreshold", &c.SessionManager.CircuitBreakerThreshold, next.SessionManager.CircuitBreakerThreshold)
+	live("sessionManager.circuitBreakerTimeout", &c.SessionManager.CircuitBreakerTimeout, next.SessionManager.CircuitBreakerTimeout)
+	live("sessionManager.maxRetryAttempts", &c.SessionManager.MaxRetryAttempts, next.SessionManager.MaxRetryAttempts)
+	live("sessionManager.cooldownPeriods", &c.SessionManager.CooldownPeriods, next.SessionManager.CooldownPeriods)
+	live("sessionManager.queueMaxWait", &c.SessionManager.QueueMaxWait, next.SessionManager.QueueMaxWait)
+	live("sessionManager.queueMaxSize", &c.SessionManager.QueueMaxSize, next.SessionManager.QueueMaxSize)
+	live("sessionManager.probeEnabled", &c.SessionManager.ProbeEnabled, next.SessionManager.ProbeEnabled)
+	live("sessionManager.probeConcurrency", &c.SessionManager.ProbeConcurrency, next.SessionManager.ProbeConcurrency)
+	live("sessionManager.routing", &c.SessionManager.Routing, next.SessionManager.Routing)
+	live("sessionManager.maxConcurrent", &c.SessionManager.MaxConcurrent, next.SessionManager.MaxConcurrent)
+	live("apiKey", &c.APIKey, next.APIKey)
+	live("proxy", &c.Proxy, next.Proxy)
+	live("corsAllowedOrigins", &c.CORSAllowedOrigins, next.CORSAllowedOrigins)
+	live("chatDelete", &c.ChatDelete, next.ChatDelete)
+	live("maxChatHistoryLength", &c.MaxChatHistoryLength, next.MaxChatHistoryLength)
+	live("retryCount", &c.RetryCount, next.RetryCount)
+	live("noRolePrefix", &c.NoRolePrefix, next.NoRolePrefix)
+	live("promptDisableArtifacts", &c.PromptDisableArtifacts, next.PromptDisableArtifacts)
+	live("thinkingMode", &c.ThinkingMode, next.ThinkingMode)
+	live("conversationAffinity", &c.ConversationAffinity, next.ConversationAffinity)
+	live("conversationAffinityTTL", &c.ConversationAffinityTTL, next.ConversationAffinityTTL)
+	live("bufferFirstToken", &c.BufferFirstToken, next.BufferFirstToken)
+	live("rateLimit", &c.RateLimit, next.RateLimit)
+	live("adminSecret", &c.AdminSecret, next.AdminSecret)
+	live("adminAccessTokenTTL", &c.AdminAccessTokenTTL, next.AdminAccessTokenTTL)
+	live("adminRefreshTokenTTL", &c.AdminRefreshTokenTTL, next.AdminRefreshTokenTTL)
+	live("adminAuth", &c.AdminAuth, next.AdminAuth)
+
+	// 以下配置在启动时使用，修改后需要重启
+	restart("address", c.Address, next.Address)
+	restart("enableMirrorApi", c.EnableMirrorApi, next.EnableMirrorApi)
+	restart("mirrorApiPrefix", c.MirrorApiPrefix, next.MirrorApiPrefix)
+	restart("configWatchInterval", c.ConfigWatchInterval, next.ConfigWatchInterval)
+	restart("apiKeysFile", c.APIKeysFile, next.APIKeysFile)
+	restart("adminUsersFile", c.AdminUsersFile, next.AdminUsersFile)
+	restart("adminTokensFile", c.AdminTokensFile, next.AdminTokensFile)
+	restart("adminAPITokensFile", c.AdminAPITokensFile, next.AdminAPITokensFile)
+	restart("tls", c.TLS, next.TLS)
+	restart("trustedProxies", c.TrustedProxies, next.TrustedProxies)
+	restart("sessionManager.stateStore", c.SessionManager.StateStore, next.SessionManager.StateStore)
+}
+
+// WatchConfigFile 定期检查配置文件，内容被外部修改时热加载，ctx 结束时退出
+// 配置来自环境变量或 configWatchInterval 为负数时不检查
+func (c *Config) WatchConfigFile(ctx context.Context) {
+	interval := c.ConfigWatchInterval
+	if c.configPath == "" || interval < 0 {
+		return
+	}
+	if interval == 0 {
+		interval = defaultConfigWatchInterval
+	}
+
+	var lastMod time.Time
+	var lastSize int64
+	if info, err := os.Stat(c.configPath); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(c.configPath)
+		if err != nil || (info.ModTime().Equal(lastMod) && info.Size() == lastSize) {
+			continue
+		}
+		lastMod, lastSize = info.ModTime(), info.Size()
+		if !c.configFileChanged() {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Detected change in %s, reloading", c.configPath))
+		if _, err := c.Reload(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to reload config: %v", err))
+		}
+	}
+}
+
+// configFileChanged 文件内容是否与最近一次加载或写入的不同，管理界面写回的修改不会触发热加载
+func (c *Config) configFileChanged() bool {
+	data, err := os.ReadFile(c.configPath)
+	if err != nil {
+		return false
+	}
+	persistMu.Lock()
+	defer persistMu.Unlock()
+	c.RwMutx.RLock()
+	defer c.RwMutx.RUnlock()
+	return contentHash(data) != c.configHash
+}
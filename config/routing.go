@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// ErrNoSessionForModel 没有任何session可以服务请求的模型或标签，排队等待也不会有结果
+var ErrNoSessionForModel = errors.New("no session can serve the requested model")
+
+// RoutingContext 选择session时的路由条件，为 nil 时不做限制
+type RoutingContext struct {
+	Model  string   // 请求的模型
+	APIKey string   // 请求使用的API Key ID
+	Tags   []string // 要求session同时具有的标签
+}
+
+// RoutingRule 路由表中的一条规则：匹配 model（以及 apiKeys）的请求只使用 groups 中的session，
+// groups 中的session都不可用时使用 fallback 中的session
+// session组可以是session的标签、层级（tier）或探测得到的 rate_limit_tier
+type RoutingRule struct {
+	Model    string   `yaml:"model" json:"model"`                         // 模型名的通配模式，如 claude-opus-*
+	APIKeys  []string `yaml:"apiKeys,omitempty" json:"apiKeys,omitempty"` // 只匹配这些API Key ID的请求，留空不限制
+	Groups   []string `yaml:"groups" json:"groups"`
+	Fallback []string `yaml:"fallback,omitempty" json:"fallback,omitempty"`
+}
+
+// matchModel 模型名是否匹配通配模式，不区分大小写
+func matchModel(pattern, model string) bool {
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(model))
+	return err == nil && matched
+}
+
+// routeFor 返回第一条匹配模型与API Key的路由规则
+func routeFor(rules []RoutingRule, routing *RoutingContext) *RoutingRule {
+	if routing.Model == "" {
+		return nil
+	}
+	for i := range rules {
+		if !matchModel(rules[i].Model, routing.Model) {
+			continue
+		}
+		if len(rules[i].APIKeys) > 0 && !containsString(rules[i].APIKeys, routing.APIKey) {
+			continue
+		}
+		return &rules[i]
+	}
+	return nil
+}
+
+// hasGroup 是否属于session组：标签、层级或 rate_limit_tier 相同，调用方需持有session锁
+func (s *SessionHealth) hasGroup(group string) bool {
+	if group == "" {
+		return false
+	}
+	return group == s.Tier || group == s.RateLimitTier || containsString(s.Tags, group)
+}
+
+// inGroup 是否属于任一session组，调用方需持有session锁
+func (s *SessionHealth) inGroup(groups []string) bool {
+	for _, group := range groups {
+		if s.hasGroup(group) {
+			return true
+		}
+	}
+	return false
+}
+
+// canServe 是否可以服务请求的模型并具有要求的全部标签，调用方需持有session锁
+func (s *SessionHealth) canServe(routing *RoutingContext) bool {
+	if routing == nil {
+		return true
+	}
+	if routing.Model != "" && len(s.AllowedModels) > 0 {
+		allowed := false
+		for _, pattern := range s.AllowedModels {
+			if matchModel(pattern, routing.Model) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	for _, tag := range routing.Tags {
+		if !s.hasGroup(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// routingStages 按路由表返回依次尝试的候选session集合：匹配规则时为 groups 与 fallback 两级，否则为全部可服务的session
+// 调用方需持有 sm.mu 读锁
+func (sm *SessionManager) routingStages(routing *RoutingContext) []map[string]*SessionHealth {
+	if routing == nil {
+		return []map[string]*SessionHealth{sm.sessions}
+	}
+
+	var groups [][]string
+	if rule := routeFor(sm.config.Routing, routing); rule != nil {
+		groups = append(groups, rule.Groups)
+		if len(rule.Fallback) > 0 {
+			groups = append(groups, rule.Fallback)
+		}
+	} else {
+		groups = append(groups, nil)
+	}
+
+	stages := make([]map[string]*SessionHealth, 0, len(groups))
+	for _, group := range groups {
+		stage := make(map[string]*SessionHealth)
+		for key, session := range sm.sessions {
+			session.mu.RLock()
+			eligible := session.canServe(routing) && (group == nil || session.inGroup(group))
+			session.mu.RUnlock()
+			if eligible {
+				stage[key] = session
+			}
+		}
+		stages = append(stages, stage)
+	}
+	return stages
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,6 @@
 package config
 
 import (
-	"errors"
 	"math/rand"
 	"sort"
 	"sync"
@@ -18,7 +17,7 @@ func (r *RoundRobinStrategy) SelectSession(sessions map[string]*SessionHealth, e
 	// 获取可用的sessions
 	availableSessions := r.getAvailableSessions(sessions, excludeKeys)
 	if len(availableSessions) == 0 {
-		return nil, errors.New("no available sessions")
+		return nil, ErrNoAvailableSessions
 	}
 
 	r.mu.Lock()
@@ -72,7 +71,7 @@ func (h *HealthPriorityStrategy) SelectSession(sessions map[string]*SessionHealt
 	// 获取可用的sessions
 	availableSessions := h.getAvailableSessions(sessions, excludeKeys)
 	if len(availableSessions) == 0 {
-		return nil, errors.New("no available sessions")
+		return nil, ErrNoAvailableSessions
 	}
 
 	// 按健康度排序，选择最健康的
@@ -119,7 +118,7 @@ func (w *WeightedStrategy) SelectSession(sessions map[string]*SessionHealth, exc
 	// 获取可用的sessions
 	availableSessions := w.getAvailableSessions(sessions, excludeKeys)
 	if len(availableSessions) == 0 {
-		return nil, errors.New("no available sessions")
+		return nil, ErrNoAvailableSessions
 	}
 
 	// 计算总权重
@@ -184,6 +183,63 @@ func (w *WeightedStrategy) isSessionUsable(session *SessionHealth) bool {
 		   time.Now().After(session.CooldownUntil)
 }
 
+// LeastConnectionsStrategy 最少连接调度策略 - 优先选择进行中请求最少的session，并按健康度加权
+type LeastConnectionsStrategy struct{}
+
+func (l *LeastConnectionsStrategy) SelectSession(sessions map[string]*SessionHealth, excludeKeys []string) (*SessionHealth, error) {
+	// 获取可用的sessions
+	availableSessions := l.getAvailableSessions(sessions, excludeKeys)
+	if len(availableSessions) == 0 {
+		return nil, ErrNoAvailableSessions
+	}
+
+	// 负载 = (进行中请求数 + 1) / 健康度，负载相同时选择最久未使用的session
+	var best *SessionHealth
+	var bestLoad float64
+	var bestLastUsed time.Time
+	for _, session := range availableSessions {
+		session.mu.RLock()
+		load := float64(session.InFlight+1) / session.HealthScore
+		lastUsed := session.LastUsed
+		session.mu.RUnlock()
+
+		if best == nil || load < bestLoad || (load == bestLoad && lastUsed.Before(bestLastUsed)) {
+			best, bestLoad, bestLastUsed = session, load, lastUsed
+		}
+	}
+
+	return best, nil
+}
+
+func (l *LeastConnectionsStrategy) GetStrategyName() string {
+	return "least_connections"
+}
+
+func (l *LeastConnectionsStrategy) getAvailableSessions(sessions map[string]*SessionHealth, excludeKeys []string) []*SessionHealth {
+	excludeMap := make(map[string]bool)
+	for _, key := range excludeKeys {
+		excludeMap[key] = true
+	}
+
+	var available []*SessionHealth
+	for _, session := range sessions {
+		if !excludeMap[session.SessionKey] && l.isSessionUsable(session) {
+			available = append(available, session)
+		}
+	}
+
+	return available
+}
+
+func (l *LeastConnectionsStrategy) isSessionUsable(session *SessionHealth) bool {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	return session.Status == StatusActive &&
+		session.HealthScore > 0.1 &&
+		time.Now().After(session.CooldownUntil)
+}
+
 // AdaptiveStrategy 自适应调度策略 - 根据当前系统状态动态选择策略
 type AdaptiveStrategy struct {
 	strategies       []ScheduleStrategy
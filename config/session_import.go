@@ -0,0 +1,207 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 批量导入支持的格式
+const (
+	ImportFormatAuto  = "auto"
+	ImportFormatLines = "lines" // 每行一个 sessionKey[:orgID]，也接受一行中逗号分隔的 SESSIONS 格式
+	ImportFormatJSON  = "json"
+	ImportFormatCSV   = "csv"
+)
+
+// SessionImportEntry 批量导入中解析出的一条session
+// Line 为所在行号，JSON 格式为数组中的序号，均从 1 开始
+type SessionImportEntry struct {
+	Line int
+	SessionInfo
+}
+
+// ParseSessionImport 按格式解析批量导入的sessions，返回实际使用的格式
+// format 为空或 auto 时自动识别：以 [ 或 { 开头为 JSON，首行为含 sessionKey 列的表头时为 CSV，否则按行解析
+func ParseSessionImport(data []byte, format string) ([]SessionImportEntry, string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if format == "" || format == ImportFormatAuto {
+		format = detectImportFormat(data)
+	}
+
+	var entries []SessionImportEntry
+	var err error
+	switch format {
+	case ImportFormatLines:
+		entries = parseImportLines(data)
+	case ImportFormatJSON:
+		entries, err = parseImportJSON(data)
+	case ImportFormatCSV:
+		entries, err = parseImportCSV(data)
+	default:
+		return nil, format, fmt.Errorf("unknown import format: %s", format)
+	}
+	if err != nil {
+		return nil, format, err
+	}
+	for i := range entries {
+		entries[i].SessionKey = strings.TrimSpace(entries[i].SessionKey)
+		entries[i].OrgID = strings.TrimSpace(entries[i].OrgID)
+	}
+	return entries, format, nil
+}
+
+// detectImportFormat 根据内容识别导入格式
+func detectImportFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return ImportFormatJSON
+	}
+	firstLine, _, _ := strings.Cut(string(trimmed), "\n")
+	reader := csv.NewReader(strings.NewReader(firstLine))
+	reader.LazyQuotes = true
+	if header, err := reader.Read(); err == nil && hasSessionKeyColumn(header) {
+		return ImportFormatCSV
+	}
+	return ImportFormatLines
+}
+
+// parseImportLines 逐行按 SESSIONS 环境变量的格式解析，跳过空行与 # 开头的注释
+func parseImportLines(data []byte) []SessionImportEntry {
+	var entries []SessionImportEntry
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		_, sessions := parseSessionEnv(line)
+		for _, session := range sessions {
+			if strings.TrimSpace(session.SessionKey) == "" {
+				continue
+			}
+			entries = append(entries, SessionImportEntry{Line: i + 1, SessionInfo: session})
+		}
+	}
+	return entries
+}
+
+// importJSONSession JSON 导入中的一条session，同时接受导出接口与健康状态中的字段名
+type importJSONSession struct {
+	SessionKey    string   `json:"sessionKey"`
+	OrgID         string   `json:"orgID"`
+	SessionKey2   string   `json:"session_key"`
+	OrgID2        string   `json:"org_id"`
+	Tags          []string `json:"tags"`
+	Tier          string   `json:"tier"`
+	AllowedModels []string `json:"allowedModels"`
+	MaxConcurrent int      `json:"maxConcurrent"`
+}
+
+// parseImportJSON 解析字符串或对象组成的数组，也接受导出接口的 {"sessions": [...]}
+func parseImportJSON(data []byte) ([]SessionImportEntry, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapper struct {
+			Sessions []json.RawMessage `json:"sessions"`
+		}
+		if wrapperErr := json.Unmarshal(data, &wrapper); wrapperErr != nil || wrapper.Sessions == nil {
+			return nil, fmt.Errorf("invalid JSON: expected an array of sessions or {\"sessions\": [...]}: %w", err)
+		}
+		items = wrapper.Sessions
+	}
+
+	entries := make([]SessionImportEntry, 0, len(items))
+	for i, item := range items {
+		entry := SessionImportEntry{Line: i + 1}
+		var key string
+		if err := json.Unmarshal(item, &key); err == nil {
+			entry.SessionKey = key
+		} else {
+			var session importJSONSession
+			if err := json.Unmarshal(item, &session); err != nil {
+				return nil, fmt.Errorf("invalid session at index %d: %w", i, err)
+			}
+			entry.SessionKey, entry.OrgID = session.SessionKey, session.OrgID
+			entry.Tags, entry.Tier, entry.AllowedModels = session.Tags, session.Tier, session.AllowedModels
+			entry.MaxConcurrent = session.MaxConcurrent
+			if entry.SessionKey == "" {
+				entry.SessionKey = session.SessionKey2
+			}
+			if entry.OrgID == "" {
+				entry.OrgID = session.OrgID2
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseImportCSV 解析 CSV，有表头时按 sessionKey/orgID 列读取，否则第一列为 sessionKey、第二列为 orgID
+func parseImportCSV(data []byte) ([]SessionImportEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	keyColumn, orgColumn := 0, 1
+	var entries []SessionImportEntry
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if first && hasSessionKeyColumn(record) {
+			orgColumn = -1
+			for i, column := range record {
+				switch {
+				case isSessionKeyColumn(column):
+					keyColumn = i
+				case isOrgIDColumn(column):
+					orgColumn = i
+				}
+			}
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		entry := SessionImportEntry{Line: line}
+		if keyColumn < len(record) {
+			entry.SessionKey = record[keyColumn]
+		}
+		if orgColumn >= 0 && orgColumn < len(record) {
+			entry.OrgID = record[orgColumn]
+		}
+		if strings.TrimSpace(entry.SessionKey) == "" && (orgColumn < 0 || strings.TrimSpace(entry.OrgID) == "") {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// hasSessionKeyColumn 是否为表头行：任一列为 sessionKey
+func hasSessionKeyColumn(record []string) bool {
+	for _, column := range record {
+		if isSessionKeyColumn(column) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSessionKeyColumn(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return name == "sessionkey" || name == "session_key"
+}
+
+func isOrgIDColumn(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return name == "orgid" || name == "org_id"
+}
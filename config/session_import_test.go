@@ -0,0 +1,157 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSessionImport(t *testing.T) {
+	// importedSession 只比较行号、sessionKey、orgID 与 tier
+	type importedSession struct {
+		Line  int
+		Key   string
+		OrgID string
+		Tier  string
+	}
+	tests := []struct {
+		name       string
+		data       string
+		format     string
+		wantFormat string
+		want       []importedSession
+		wantErr    bool
+	}{
+		{
+			name:       "lines with comments and blank lines",
+			data:       "# exported sessions\nsk-ant-aaaaaaaaaa:org-1\n\n  sk-ant-bbbbbbbbbb  \n",
+			wantFormat: ImportFormatLines,
+			want:       []importedSession{{Line: 2, Key: "sk-ant-aaaaaaaaaa", OrgID: "org-1"}, {Line: 4, Key: "sk-ant-bbbbbbbbbb"}},
+		},
+		{
+			name:       "lines with BOM and CRLF",
+			data:       "\xef\xbb\xbfsk-ant-aaaaaaaaaa:org-1\r\nsk-ant-bbbbbbbbbb\r\n",
+			wantFormat: ImportFormatLines,
+			want:       []importedSession{{Line: 1, Key: "sk-ant-aaaaaaaaaa", OrgID: "org-1"}, {Line: 2, Key: "sk-ant-bbbbbbbbbb"}},
+		},
+		{
+			name:       "comma separated SESSIONS line",
+			data:       "sk-ant-aaaaaaaaaa:org-1,,sk-ant-bbbbbbbbbb",
+			wantFormat: ImportFormatLines,
+			want:       []importedSession{{Line: 1, Key: "sk-ant-aaaaaaaaaa", OrgID: "org-1"}, {Line: 1, Key: "sk-ant-bbbbbbbbbb"}},
+		},
+		{
+			name:       "malformed lines without a key are skipped",
+			data:       ":org-only\n  :  \nsk-ant-aaaaaaaaaa",
+			wantFormat: ImportFormatLines,
+			want:       []importedSession{{Line: 3, Key: "sk-ant-aaaaaaaaaa"}},
+		},
+		{
+			// 掩码后的key原样保留并带上行号，由导入接口按无效key逐行报告，不会被静默丢弃
+			name:       "masked keys are kept for validation",
+			data:       "sk-a****aaaa:org-1\nsk-ant-bbbbbbbbbb",
+			wantFormat: ImportFormatLines,
+			want:       []importedSession{{Line: 1, Key: "sk-a****aaaa", OrgID: "org-1"}, {Line: 2, Key: "sk-ant-bbbbbbbbbb"}},
+		},
+		{
+			name:       "JSON strings and objects",
+			data:       `["sk-ant-aaaaaaaaaa", {"sessionKey": "sk-ant-bbbbbbbbbb", "orgID": "org-2", "tier": "max"}, {"session_key": " sk-ant-cccccccccc ", "org_id": "org-3"}]`,
+			wantFormat: ImportFormatJSON,
+			want: []importedSession{
+				{Line: 1, Key: "sk-ant-aaaaaaaaaa"},
+				{Line: 2, Key: "sk-ant-bbbbbbbbbb", OrgID: "org-2", Tier: "max"},
+				{Line: 3, Key: "sk-ant-cccccccccc", OrgID: "org-3"},
+			},
+		},
+		{
+			name:       "JSON export wrapper with masked key",
+			data:       `{"sessions": [{"sessionKey": "sk-a****aaaa", "orgID": "org-1"}]}`,
+			wantFormat: ImportFormatJSON,
+			want:       []importedSession{{Line: 1, Key: "sk-a****aaaa", OrgID: "org-1"}},
+		},
+		{
+			name:       "truncated JSON",
+			data:       `["sk-ant-aaaaaaaaaa"`,
+			wantFormat: ImportFormatJSON,
+			wantErr:    true,
+		},
+		{
+			name:       "JSON item of the wrong type",
+			data:       `["sk-ant-aaaaaaaaaa", 42]`,
+			wantFormat: ImportFormatJSON,
+			wantErr:    true,
+		},
+		{
+			name:       "JSON object without sessions",
+			data:       `{"keys": []}`,
+			wantFormat: ImportFormatJSON,
+			wantErr:    true,
+		},
+		{
+			name:       "CSV with reordered header",
+			data:       "org_id,note,sessionKey\norg-1,first,sk-ant-aaaaaaaaaa\n# skipped\n,missing key,\n",
+			format:     ImportFormatCSV,
+			wantFormat: ImportFormatCSV,
+			want:       []importedSession{{Line: 2, Key: "sk-ant-aaaaaaaaaa", OrgID: "org-1"}},
+		},
+		{
+			name:       "CSV header with sessionKey after orgID is detected",
+			data:       "orgID,sessionKey\r\norg-1,sk-ant-aaaaaaaaaa\r\n,sk-ant-bbbbbbbbbb\r\n",
+			wantFormat: ImportFormatCSV,
+			want:       []importedSession{{Line: 2, Key: "sk-ant-aaaaaaaaaa", OrgID: "org-1"}, {Line: 3, Key: "sk-ant-bbbbbbbbbb"}},
+		},
+		{
+			name:       "quoted CSV header is detected",
+			data:       "\"sessionKey\",\"orgID\"\n\"sk-ant-aaaaaaaaaa\",\"org-1\"\n",
+			wantFormat: ImportFormatCSV,
+			want:       []importedSession{{Line: 2, Key: "sk-ant-aaaaaaaaaa", OrgID: "org-1"}},
+		},
+		{
+			name:       "CSV row with only an orgID is reported",
+			data:       "sessionKey,orgID\n,org-1\n",
+			wantFormat: ImportFormatCSV,
+			want:       []importedSession{{Line: 2, OrgID: "org-1"}},
+		},
+		{
+			name:       "CSV without header",
+			data:       "sk-ant-aaaaaaaaaa,org-1\n\"sk-ant-bbbbbbbbbb\"\n",
+			format:     ImportFormatCSV,
+			wantFormat: ImportFormatCSV,
+			want:       []importedSession{{Line: 1, Key: "sk-ant-aaaaaaaaaa", OrgID: "org-1"}, {Line: 2, Key: "sk-ant-bbbbbbbbbb"}},
+		},
+		{
+			name:       "malformed CSV quoting",
+			data:       "sessionKey,orgID\n\"sk-ant-aaaaaaaaaa,org-1\n",
+			wantFormat: ImportFormatCSV,
+			wantErr:    true,
+		},
+		{
+			name:       "unknown format",
+			data:       "sk-ant-aaaaaaaaaa",
+			format:     "xml",
+			wantFormat: "xml",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, format, err := ParseSessionImport([]byte(tt.data), tt.format)
+			if format != tt.wantFormat {
+				t.Fatalf("format = %q, want %q", format, tt.wantFormat)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make([]importedSession, 0, len(entries))
+			for _, entry := range entries {
+				got = append(got, importedSession{Line: entry.Line, Key: entry.SessionKey, OrgID: entry.OrgID, Tier: entry.Tier})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("entries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound session不存在
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrInvalidCooldown 强制冷却的时长必须为正数
+	ErrInvalidCooldown = errors.New("cooldown duration must be positive")
+	// ErrSessionAtCapacity session进行中的请求已达到并发上限
+	ErrSessionAtCapacity = errors.New("session is at its concurrency limit")
+)
+
+// isHeld 是否被管理员停用或正在排空，此时错误与恢复都不改变状态，调用方需持有session锁
+func (s *SessionHealth) isHeld() bool {
+	return s.Status == StatusDisabled || s.Status == StatusDraining
+}
+
+// withSession 在session写锁下执行 fn，返回操作后的健康状态副本
+func (sm *SessionManager) withSession(sessionKey string, fn func(session *SessionHealth) error) (*SessionHealth, error) {
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionKey]
+	sm.mu.RUnlock()
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	session.mu.Lock()
+	err := fn(session)
+	session.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// 状态变化可能让等待中的请求有了可用session
+	sm.queue.notify()
+	return session.clone(), nil
+}
+
+// ResetSession 清空session的计数、错误记录、冷却与熔断状态，健康度恢复为1
+// 停用或排空中的session保持原状态，需要单独启用
+func (sm *SessionManager) ResetSession(sessionKey string) (*SessionHealth, error) {
+	config := sm.GetConfig()
+	return sm.withSession(sessionKey, func(session *SessionHealth) error {
+		session.ErrorCount = 0
+		session.SuccessCount = 0
+		session.TotalRequests = 0
+		session.AvgResponseTime = 0
+		session.ErrorTypes = make(map[ErrorType]int)
+		session.RecentErrors = make([]ErrorRecord, 0, 10)
+		session.LastError = time.Time{}
+		session.CooldownUntil = time.Time{}
+		session.RateLimitType = ""
+		session.RateLimitResetsAt = time.Time{}
+		session.HealthScore = 1.0
+		if config.CircuitBreakerEnabled {
+			session.CircuitBreaker = newCircuitBreaker(config)
+		} else {
+			session.CircuitBreaker = nil
+		}
+		if !session.isHeld() {
+			session.Status = StatusActive
+		}
+		return nil
+	})
+}
+
+// EnableSession 重新启用停用或排空中的session，仍在冷却或熔断中时恢复为对应状态
+func (sm *SessionManager) EnableSession(sessionKey string) (*SessionHealth, error) {
+	return sm.withSession(sessionKey, func(session *SessionHealth) error {
+		if !session.isHeld() {
+			return nil
+		}
+		switch {
+		case session.CircuitBreaker != nil && session.CircuitBreaker.State == CircuitOpen:
+			session.Status = StatusCircuitOpen
+		case time.Now().Before(session.CooldownUntil):
+			session.Status = StatusCooling
+		default:
+			session.Status = StatusActive
+			sm.restoreHealthScore(session)
+		}
+		return nil
+	})
+}
+
+// DisableSession 停用session，立即退出调度；进行中的请求不受影响
+func (sm *SessionManager) DisableSession(sessionKey string) (*SessionHealth, error) {
+	return sm.withSession(sessionKey, func(session *SessionHealth) error {
+		session.Status = StatusDisabled
+		return nil
+	})
+}
+
+// DrainSession 排空session：不再分配新请求，进行中的请求完成后转为停用
+func (sm *SessionManager) DrainSession(sessionKey string) (*SessionHealth, error) {
+	return sm.withSession(sessionKey, func(session *SessionHealth) error {
+		if session.Status == StatusDisabled {
+			return nil
+		}
+		if session.InFlight > 0 {
+			session.Status = StatusDraining
+		} else {
+			session.Status = StatusDisabled
+		}
+		return nil
+	})
+}
+
+// ForceCooldown 让session冷却指定时长，停用或排空中的session只更新冷却时间
+func (sm *SessionManager) ForceCooldown(sessionKey string, duration time.Duration) (*SessionHealth, error) {
+	if duration <= 0 {
+		return nil, ErrInvalidCooldown
+	}
+	return sm.withSession(sessionKey, func(session *SessionHealth) error {
+		session.CooldownUntil = time.Now().Add(duration)
+		if !session.isHeld() {
+			session.Status = StatusCooling
+			sm.updateHealthScore(session)
+		}
+		return nil
+	})
+}
+
+// atCapacity 进行中的请求是否已达到并发上限，session未单独设置时使用 defaultLimit，调用方需持有session锁
+func (s *SessionHealth) atCapacity(defaultLimit int) bool {
+	limit := s.MaxConcurrent
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	return limit > 0 && s.InFlight >= limit
+}
+
+// withFullSessions 返回加上已达到并发上限的session后的排除列表，调用方需持有 sm.mu 读锁
+func (sm *SessionManager) withFullSessions(excludeKeys []string) []string {
+	var full []string
+	for key, session := range sm.sessions {
+		session.mu.RLock()
+		if session.atCapacity(sm.config.MaxConcurrent) {
+			full = append(full, key)
+		}
+		session.mu.RUnlock()
+	}
+	if len(full) == 0 {
+		return excludeKeys
+	}
+	return append(append(make([]string, 0, len(excludeKeys)+len(full)), excludeKeys...), full...)
+}
+
+// AcquireSession 为请求占用session的一个并发名额，已达到并发上限时返回 ErrSessionAtCapacity
+// 成功后调用方需在请求结束时调用 ReleaseSession
+func (sm *SessionManager) AcquireSession(sessionKey string) error {
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionKey]
+	defaultLimit := sm.config.MaxConcurrent
+	sm.mu.RUnlock()
+	if !exists {
+		return ErrSessionNotFound
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.atCapacity(defaultLimit) {
+		return ErrSessionAtCapacity
+	}
+	session.InFlight++
+	return nil
+}
+
+// ReleaseSession 归还session的并发名额并唤醒等待队列，排空中的session在最后一个请求完成后转为停用
+func (sm *SessionManager) ReleaseSession(sessionKey string) {
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionKey]
+	sm.mu.RUnlock()
+	if !exists {
+		return
+	}
+	session.mu.Lock()
+	if session.InFlight > 0 {
+		session.InFlight--
+	}
+	if session.InFlight == 0 && session.Status == StatusDraining {
+		session.Status = StatusDisabled
+	}
+	session.mu.Unlock()
+	sm.queue.notify()
+}
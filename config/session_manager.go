@@ -9,6 +9,7 @@ import (
 
 // SessionHealth Session健康状态
 type SessionHealth struct {
+	ID              string                 `json:"id"` // 由sessionKey派生，见 SessionID
 	SessionKey      string                 `json:"session_key"`
 	OrgID           string                 `json:"org_id"`
 	HealthScore     float64                `json:"health_score"`
@@ -24,6 +25,14 @@ type SessionHealth struct {
 	RecentErrors    []ErrorRecord          `json:"recent_errors,omitempty"`
 	Weights         float64                `json:"weights"`
 	CircuitBreaker  *CircuitBreaker        `json:"circuit_breaker,omitempty"`
+	RateLimitType   string                 `json:"rate_limit_type,omitempty"`
+	RateLimitResetsAt time.Time            `json:"rate_limit_resets_at,omitempty"`
+	RateLimitTier   string                 `json:"rate_limit_tier,omitempty"` // 主动探测得到的组织限额层级
+	Tags            []string               `json:"tags,omitempty"`
+	Tier            string                 `json:"tier,omitempty"`
+	AllowedModels   []string               `json:"allowed_models,omitempty"`
+	InFlight        int                    `json:"in_flight"` // 正在处理的请求数
+	MaxConcurrent   int                    `json:"max_concurrent,omitempty"` // 单独设置的并发上限，0 表示使用全局设置
 	mu              sync.RWMutex           `json:"-"`
 }
 
@@ -35,6 +44,8 @@ const (
 	StatusCooling
 	StatusFailed
 	StatusCircuitOpen
+	StatusDisabled // 管理员手动停用，不参与调度
+	StatusDraining // 不再接收新请求，进行中的请求完成后转为停用
 )
 
 func (s SessionStatus) String() string {
@@ -47,6 +58,10 @@ func (s SessionStatus) String() string {
 		return "failed"
 	case StatusCircuitOpen:
 		return "circuit_open"
+	case StatusDisabled:
+		return "disabled"
+	case StatusDraining:
+		return "draining"
 	default:
 		return "unknown"
 	}
@@ -87,6 +102,13 @@ type SessionManager struct {
 	stats           *ManagerStats
 	lastHealthCheck time.Time
 	startTime       time.Time
+	clients         *clientPool
+	queue           *sessionQueue
+	store           StateStore
+	stopSnapshots   chan struct{}
+	stopProbes      chan struct{}
+	closeOnce       sync.Once
+	onOrgIDChanged  func(sessionKey, orgID string) // 探测发现的orgID同步回配置
 }
 
 // CallRecord 调用记录
@@ -107,6 +129,7 @@ type ManagerStats struct {
 	SessionsActive   int                      `json:"sessions_active"`
 	SessionsCooling  int                      `json:"sessions_cooling"`
 	SessionsFailed   int                      `json:"sessions_failed"`
+	SessionsDisabled int                      `json:"sessions_disabled"`
 	LastReset        time.Time                `json:"last_reset"`
 	CallRecords      []CallRecord             `json:"call_records"`
 	CallCountByHour  map[string]int           `json:"call_count_by_hour"`
@@ -132,27 +155,22 @@ func NewSessionManager(sessions []SessionInfo, config SessionManagerConfig) *Ses
 		},
 		lastHealthCheck: time.Now(),
 		startTime:       time.Now(),
+		clients:         newClientPool(),
+		queue:           newSessionQueue(),
 	}
 
 	// 设置默认配置
-	if config.HealthCheckInterval == 0 {
-		sm.config.HealthCheckInterval = 30 * time.Second
-	}
-	if config.MinHealthScore == 0 {
-		sm.config.MinHealthScore = 0.5
-	}
-	if config.MaxRetryAttempts == 0 {
-		sm.config.MaxRetryAttempts = 3
-	}
-	if config.CooldownPeriods == nil {
-		sm.config.CooldownPeriods = getDefaultCooldownPeriods()
-	}
+	sm.config = withDefaults(config)
 
 	// 初始化sessions
 	for _, session := range sessions {
 		sessionHealth := &SessionHealth{
 			SessionKey:      session.SessionKey,
 			OrgID:           session.OrgID,
+			Tags:            session.Tags,
+			Tier:            session.Tier,
+			AllowedModels:   session.AllowedModels,
+			MaxConcurrent:   session.MaxConcurrent,
 			HealthScore:     1.0,
 			Status:          StatusActive,
 			LastUsed:        time.Now(),
@@ -163,11 +181,7 @@ func NewSessionManager(sessions []SessionInfo, config SessionManagerConfig) *Ses
 		
 		// 只有在启用熔断器时才初始化熔断器
 		if config.CircuitBreakerEnabled {
-			sessionHealth.CircuitBreaker = &CircuitBreaker{
-				State:     CircuitClosed,
-				Threshold: 5,
-				Timeout:   60 * time.Second,
-			}
+			sessionHealth.CircuitBreaker = newCircuitBreaker(sm.config)
 		}
 		
 		sm.sessions[session.SessionKey] = sessionHealth
@@ -176,9 +190,63 @@ func NewSessionManager(sessions []SessionInfo, config SessionManagerConfig) *Ses
 	// 选择调度策略
 	sm.scheduler = sm.createScheduler()
 
+	// 恢复持久化的健康状态与统计
+	sm.initStateStore()
+
+	// 主动探测session，是否执行由 probeEnabled 决定，可在线切换
+	sm.stopProbes = make(chan struct{})
+	go sm.runProbes()
+
 	return sm
 }
 
+// withDefaults 为未设置的配置项填充默认值，冷却时间表复制一份，避免与调用方共享
+func withDefaults(config SessionManagerConfig) SessionManagerConfig {
+	if config.HealthCheckInterval == 0 {
+		config.HealthCheckInterval = 30 * time.Second
+	}
+	if config.MinHealthScore == 0 {
+		config.MinHealthScore = 0.5
+	}
+	if config.MaxRetryAttempts == 0 {
+		config.MaxRetryAttempts = 3
+	}
+	if config.CircuitBreakerThreshold <= 0 {
+		config.CircuitBreakerThreshold = 5
+	}
+	if config.CircuitBreakerTimeout <= 0 {
+		config.CircuitBreakerTimeout = 60 * time.Second
+	}
+	if config.CooldownPeriods == nil {
+		config.CooldownPeriods = getDefaultCooldownPeriods()
+	} else {
+		cooldownPeriods := make(map[string]time.Duration, len(config.CooldownPeriods))
+		for errorType, period := range config.CooldownPeriods {
+			cooldownPeriods[errorType] = period
+		}
+		config.CooldownPeriods = cooldownPeriods
+	}
+	if config.QueueMaxWait == 0 {
+		config.QueueMaxWait = defaultQueueMaxWait
+	}
+	if config.QueueMaxSize <= 0 {
+		config.QueueMaxSize = defaultQueueMaxSize
+	}
+	if config.ProbeConcurrency <= 0 {
+		config.ProbeConcurrency = defaultProbeConcurrency
+	}
+	return config
+}
+
+// newCircuitBreaker 按配置的阈值创建熔断器
+func newCircuitBreaker(config SessionManagerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		State:     CircuitClosed,
+		Threshold: config.CircuitBreakerThreshold,
+		Timeout:   config.CircuitBreakerTimeout,
+	}
+}
+
 // getDefaultCooldownPeriods 获取默认冷却时间
 func getDefaultCooldownPeriods() map[string]time.Duration {
 	return map[string]time.Duration{
@@ -199,13 +267,16 @@ func (sm *SessionManager) createScheduler() ScheduleStrategy {
 		return &WeightedStrategy{}
 	case "adaptive":
 		return NewAdaptiveStrategy()
+	case "least_connections":
+		return &LeastConnectionsStrategy{}
 	default:
 		return &RoundRobinStrategy{}
 	}
 }
 
-// SelectBestSession 选择最佳session
-func (sm *SessionManager) SelectBestSession(excludeKeys []string) (*SessionHealth, error) {
+// SelectBestSession 按路由条件选择最佳session，routing 为 nil 时在全部session中选择
+// 路由表中的规则匹配时先在 groups 中选择，都不可用时再在 fallback 中选择
+func (sm *SessionManager) SelectBestSession(routing *RoutingContext, excludeKeys []string) (*SessionHealth, error) {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -214,8 +285,24 @@ func (sm *SessionManager) SelectBestSession(excludeKeys []string) (*SessionHealt
 		go sm.performHealthCheck()
 	}
 
-	// 使用调度策略选择session
-	session, err := sm.scheduler.SelectSession(sm.sessions, excludeKeys)
+	// 已达到并发上限的session不参与本次调度
+	excludeKeys = sm.withFullSessions(excludeKeys)
+
+	// 使用调度策略在每一级候选中选择session
+	var session *SessionHealth
+	err := ErrNoAvailableSessions
+	if routing != nil && len(sm.sessions) > 0 {
+		err = ErrNoSessionForModel
+	}
+	for _, candidates := range sm.routingStages(routing) {
+		if len(candidates) == 0 {
+			continue
+		}
+		session, err = sm.scheduler.SelectSession(candidates, excludeKeys)
+		if err == nil {
+			break
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -232,8 +319,24 @@ func (sm *SessionManager) SelectBestSession(excludeKeys []string) (*SessionHealt
 
 // RecordSuccess 记录成功请求
 func (sm *SessionManager) RecordSuccess(sessionKey string, responseTime time.Duration) {
+	sm.recordSuccess(sessionKey, responseTime, false)
+}
+
+// recordProbeSuccess 记录成功的后台探测：只更新健康度、熔断器与限额状态，
+// 不计入请求计数、调用记录与平均响应时间
+func (sm *SessionManager) recordProbeSuccess(sessionKey string) {
+	sm.recordSuccess(sessionKey, 0, true)
+}
+
+// recordProbeError 记录失败的后台探测：更新健康度、冷却与熔断器，不计入请求计数与调用记录
+func (sm *SessionManager) recordProbeError(sessionKey string, errorType ErrorType, err error) {
+	sm.recordError(sessionKey, errorType, err, "", time.Time{}, true)
+}
+
+func (sm *SessionManager) recordSuccess(sessionKey string, responseTime time.Duration, probe bool) {
 	sm.mu.RLock()
 	session, exists := sm.sessions[sessionKey]
+	config := sm.config
 	sm.mu.RUnlock()
 
 	if !exists {
@@ -243,30 +346,39 @@ func (sm *SessionManager) RecordSuccess(sessionKey string, responseTime time.Dur
 	session.mu.Lock()
 	defer session.mu.Unlock()
 
-	session.SuccessCount++
-	session.TotalRequests++
+	if !probe {
+		session.SuccessCount++
+		session.TotalRequests++
+	}
+
+	// 限额已重置
+	if !session.RateLimitResetsAt.IsZero() && time.Now().After(session.RateLimitResetsAt) {
+		session.RateLimitType = ""
+		session.RateLimitResetsAt = time.Time{}
+	}
 	
-	// 更新平均响应时间
-	if session.AvgResponseTime == 0 {
-		session.AvgResponseTime = responseTime
-	} else {
-		session.AvgResponseTime = (session.AvgResponseTime + responseTime) / 2
+	// 更新平均响应时间，探测的延迟不代表实际请求
+	if !probe {
+		if session.AvgResponseTime == 0 {
+			session.AvgResponseTime = responseTime
+		} else {
+			session.AvgResponseTime = (session.AvgResponseTime + responseTime) / 2
+		}
 	}
 
 	// 重置熔断器
-	if sm.config.CircuitBreakerEnabled && session.CircuitBreaker != nil {
+	if config.CircuitBreakerEnabled && session.CircuitBreaker != nil {
 		sm.resetCircuitBreaker(session)
-	} else if sm.config.CircuitBreakerEnabled && session.CircuitBreaker == nil {
+	} else if config.CircuitBreakerEnabled && session.CircuitBreaker == nil {
 		// 如果熔断器启用但为空，初始化熔断器
-		session.CircuitBreaker = &CircuitBreaker{
-			State:     CircuitClosed,
-			Threshold: 5,
-			Timeout:   60 * time.Second,
-		}
+		session.CircuitBreaker = newCircuitBreaker(config)
 	}
 
 	// 重新计算健康度
 	sm.updateHealthScore(session)
+	if probe {
+		return
+	}
 
 	// 更新统计
 	sm.updateStats(true, responseTime, ErrorOther)
@@ -277,6 +389,16 @@ func (sm *SessionManager) RecordSuccess(sessionKey string, responseTime time.Dur
 
 // RecordError 记录错误
 func (sm *SessionManager) RecordError(sessionKey string, errorType ErrorType, err error) {
+	sm.recordError(sessionKey, errorType, err, "", time.Time{}, false)
+}
+
+// RecordRateLimitError 记录限流错误，上游给出重置时间时冷却到该时间
+func (sm *SessionManager) RecordRateLimitError(sessionKey string, err error, limitType string, resetsAt time.Time) {
+	sm.recordError(sessionKey, ErrorRateLimit, err, limitType, resetsAt, false)
+}
+
+// UpdateRateLimit 记录成功请求中上游返回的限额信息，已超出限额时冷却到重置时间
+func (sm *SessionManager) UpdateRateLimit(sessionKey string, limitType string, resetsAt time.Time) {
 	sm.mu.RLock()
 	session, exists := sm.sessions[sessionKey]
 	sm.mu.RUnlock()
@@ -288,10 +410,36 @@ func (sm *SessionManager) RecordError(sessionKey string, errorType ErrorType, er
 	session.mu.Lock()
 	defer session.mu.Unlock()
 
-	session.ErrorCount++
-	session.TotalRequests++
+	session.RateLimitType = limitType
+	session.RateLimitResetsAt = resetsAt
+	if limitType == "exceeded_limit" && resetsAt.After(time.Now()) {
+		session.CooldownUntil = resetsAt
+		if !session.isHeld() {
+			session.Status = StatusCooling
+		}
+	}
+}
+
+// recordError 记录错误，probe 为 true 时不计入请求计数与调用记录
+func (sm *SessionManager) recordError(sessionKey string, errorType ErrorType, err error, limitType string, resetsAt time.Time, probe bool) {
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionKey]
+	config := sm.config
+	sm.mu.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if !probe {
+		session.ErrorCount++
+		session.TotalRequests++
+		session.ErrorTypes[errorType]++
+	}
 	session.LastError = time.Now()
-	session.ErrorTypes[errorType]++
 
 	// 添加错误记录
 	session.RecentErrors = append(session.RecentErrors, ErrorRecord{
@@ -305,24 +453,30 @@ func (sm *SessionManager) RecordError(sessionKey string, errorType ErrorType, er
 		session.RecentErrors = session.RecentErrors[1:]
 	}
 
-	// 设置冷却时间
-	sm.applyCooldown(session, errorType)
+	// 设置冷却时间，上游给出限额重置时间时以其为准
+	applyCooldown(session, errorType, config.CooldownPeriods)
+	if limitType != "" || !resetsAt.IsZero() {
+		session.RateLimitType = limitType
+		session.RateLimitResetsAt = resetsAt
+	}
+	if resetsAt.After(time.Now()) {
+		session.CooldownUntil = resetsAt
+	}
 
 	// 更新熔断器
-	if sm.config.CircuitBreakerEnabled {
+	if config.CircuitBreakerEnabled {
 		if session.CircuitBreaker == nil {
 			// 如果熔断器启用但为空，初始化熔断器
-			session.CircuitBreaker = &CircuitBreaker{
-				State:     CircuitClosed,
-				Threshold: 5,
-				Timeout:   60 * time.Second,
-			}
+			session.CircuitBreaker = newCircuitBreaker(config)
 		}
 		sm.updateCircuitBreaker(session)
 	}
 
 	// 重新计算健康度
 	sm.updateHealthScore(session)
+	if probe {
+		return
+	}
 
 	// 更新统计
 	sm.updateStats(false, 0, errorType)
@@ -380,14 +534,16 @@ func (sm *SessionManager) updateHealthScore(session *SessionHealth) {
 }
 
 // applyCooldown 应用冷却时间
-func (sm *SessionManager) applyCooldown(session *SessionHealth, errorType ErrorType) {
-	cooldownDuration, exists := sm.config.CooldownPeriods[errorType.String()]
+func applyCooldown(session *SessionHealth, errorType ErrorType, cooldownPeriods map[string]time.Duration) {
+	cooldownDuration, exists := cooldownPeriods[errorType.String()]
 	if !exists {
 		cooldownDuration = 1 * time.Minute // 默认冷却时间
 	}
 
 	session.CooldownUntil = time.Now().Add(cooldownDuration)
-	session.Status = StatusCooling
+	if !session.isHeld() {
+		session.Status = StatusCooling
+	}
 }
 
 // updateCircuitBreaker 更新熔断器状态
@@ -403,7 +559,9 @@ func (sm *SessionManager) updateCircuitBreaker(session *SessionHealth) {
 	if cb.State == CircuitClosed && cb.FailureCount >= cb.Threshold {
 		cb.State = CircuitOpen
 		cb.NextAttempt = time.Now().Add(cb.Timeout)
-		session.Status = StatusCircuitOpen
+		if !session.isHeld() {
+			session.Status = StatusCircuitOpen
+		}
 	}
 }
 
@@ -433,13 +591,17 @@ func (sm *SessionManager) performHealthCheck() {
 	sm.mu.Unlock()
 
 	now := time.Now()
+	recovered := false
 	
+	sm.mu.RLock()
 	for _, session := range sm.sessions {
 		session.mu.Lock()
 		
 		// 检查冷却时间是否结束
 		if session.Status == StatusCooling && now.After(session.CooldownUntil) {
 			session.Status = StatusActive
+			sm.restoreHealthScore(session)
+			recovered = true
 		}
 
 		// 检查熔断器是否可以进入半开状态
@@ -447,11 +609,26 @@ func (sm *SessionManager) performHealthCheck() {
 			if now.After(session.CircuitBreaker.NextAttempt) {
 				session.CircuitBreaker.State = CircuitHalfOpen
 				session.Status = StatusActive
+				sm.restoreHealthScore(session)
+				recovered = true
 			}
 		}
 
 		session.mu.Unlock()
 	}
+	sm.mu.RUnlock()
+
+	// 有session恢复时唤醒等待队列
+	if recovered {
+		sm.queue.notify()
+	}
+}
+
+// restoreHealthScore 冷却或熔断结束后重新计算健康度，并至少恢复到最小健康分数，
+// 否则只有错误记录的session健康度为0，永远不会再被调度
+func (sm *SessionManager) restoreHealthScore(session *SessionHealth) {
+	sm.updateHealthScore(session)
+	session.HealthScore = math.Max(session.HealthScore, sm.config.MinHealthScore)
 }
 
 // updateStats 更新统计信息
@@ -473,23 +650,28 @@ func (sm *SessionManager) updateStats(success bool, responseTime time.Duration,
 		sm.stats.FailedRequests++
 		sm.stats.ErrorsByType[errorType]++
 	}
+}
 
-	// 更新session状态统计
-	active, cooling, failed := 0, 0, 0
+// sessionStatusCounts 统计各状态的session数量，不能在持有session锁时调用
+func (sm *SessionManager) sessionStatusCounts() (active, cooling, failed, disabled int) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
 	for _, session := range sm.sessions {
-		switch session.Status {
+		session.mu.RLock()
+		status := session.Status
+		session.mu.RUnlock()
+		switch status {
 		case StatusActive:
 			active++
 		case StatusCooling:
 			cooling++
 		case StatusFailed, StatusCircuitOpen:
 			failed++
+		case StatusDisabled, StatusDraining:
+			disabled++
 		}
 	}
-	
-	sm.stats.SessionsActive = active
-	sm.stats.SessionsCooling = cooling
-	sm.stats.SessionsFailed = failed
+	return active, cooling, failed, disabled
 }
 
 // GetSessionsHealth 获取所有session健康状态
@@ -500,8 +682,7 @@ func (sm *SessionManager) GetSessionsHealth() []*SessionHealth {
 	sessions := make([]*SessionHealth, 0, len(sm.sessions))
 	for _, session := range sm.sessions {
 		// 创建副本以避免并发问题
-		sessionCopy := *session
-		sessions = append(sessions, &sessionCopy)
+		sessions = append(sessions, session.clone())
 	}
 
 	// 按健康度排序
@@ -512,16 +693,68 @@ func (sm *SessionManager) GetSessionsHealth() []*SessionHealth {
 	return sessions
 }
 
-// GetStats 获取统计信息
+// clone 在读锁下复制健康状态，副本不共享锁、map 与切片
+func (s *SessionHealth) clone() *SessionHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessionCopy := &SessionHealth{
+		ID:              SessionID(s.SessionKey),
+		SessionKey:      s.SessionKey,
+		OrgID:           s.OrgID,
+		HealthScore:     s.HealthScore,
+		Status:          s.Status,
+		LastUsed:        s.LastUsed,
+		LastError:       s.LastError,
+		CooldownUntil:   s.CooldownUntil,
+		ErrorCount:      s.ErrorCount,
+		SuccessCount:    s.SuccessCount,
+		TotalRequests:   s.TotalRequests,
+		AvgResponseTime: s.AvgResponseTime,
+		ErrorTypes:      make(map[ErrorType]int, len(s.ErrorTypes)),
+		RecentErrors:    append([]ErrorRecord(nil), s.RecentErrors...),
+		Weights:         s.Weights,
+		RateLimitType:   s.RateLimitType,
+		RateLimitResetsAt: s.RateLimitResetsAt,
+		RateLimitTier:   s.RateLimitTier,
+		Tags:            append([]string(nil), s.Tags...),
+		Tier:            s.Tier,
+		AllowedModels:   append([]string(nil), s.AllowedModels...),
+		InFlight:        s.InFlight,
+		MaxConcurrent:   s.MaxConcurrent,
+	}
+	for k, v := range s.ErrorTypes {
+		sessionCopy.ErrorTypes[k] = v
+	}
+	if s.CircuitBreaker != nil {
+		cb := *s.CircuitBreaker
+		sessionCopy.CircuitBreaker = &cb
+	}
+	return sessionCopy
+}
+
+// GetStats 获取统计信息，session状态统计在调用时计算
 func (sm *SessionManager) GetStats() *ManagerStats {
+	active, cooling, failed, disabled := sm.sessionStatusCounts()
+
 	sm.stats.mu.RLock()
 	defer sm.stats.mu.RUnlock()
 
 	// 返回副本
-	statsCopy := *sm.stats
-	statsCopy.ErrorsByType = make(map[ErrorType]int64)
-	statsCopy.CallRecords = make([]CallRecord, len(sm.stats.CallRecords))
-	statsCopy.CallCountByHour = make(map[string]int)
+	statsCopy := ManagerStats{
+		TotalRequests:   sm.stats.TotalRequests,
+		SuccessfulReqs:  sm.stats.SuccessfulReqs,
+		FailedRequests:  sm.stats.FailedRequests,
+		AverageLatency:  sm.stats.AverageLatency,
+		ErrorsByType:    make(map[ErrorType]int64),
+		SessionsActive:  active,
+		SessionsCooling: cooling,
+		SessionsFailed:  failed,
+		SessionsDisabled: disabled,
+		LastReset:       sm.stats.LastReset,
+		CallRecords:     make([]CallRecord, len(sm.stats.CallRecords)),
+		CallCountByHour: make(map[string]int),
+	}
 	
 	for k, v := range sm.stats.ErrorsByType {
 		statsCopy.ErrorsByType[k] = v
@@ -563,6 +796,8 @@ func (sm *SessionManager) addCallRecord(sessionKey string, success bool, latency
 
 // GetMaxRetryAttempts 获取最大重试次数
 func (sm *SessionManager) GetMaxRetryAttempts() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
 	return sm.config.MaxRetryAttempts
 }
 
@@ -575,7 +810,11 @@ func (sm *SessionManager) GetStartTime() time.Time {
 func (sm *SessionManager) AddSession(sessionInfo SessionInfo) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	sm.addSessionLocked(sessionInfo)
+}
 
+// addSessionLocked 添加session，调用方需持有写锁
+func (sm *SessionManager) addSessionLocked(sessionInfo SessionInfo) {
 	// 检查session是否已存在
 	if _, exists := sm.sessions[sessionInfo.SessionKey]; exists {
 		return
@@ -585,6 +824,10 @@ func (sm *SessionManager) AddSession(sessionInfo SessionInfo) {
 	sessionHealth := &SessionHealth{
 		SessionKey:      sessionInfo.SessionKey,
 		OrgID:           sessionInfo.OrgID,
+		Tags:            sessionInfo.Tags,
+		Tier:            sessionInfo.Tier,
+		AllowedModels:   sessionInfo.AllowedModels,
+		MaxConcurrent:   sessionInfo.MaxConcurrent,
 		HealthScore:     1.0,
 		Status:          StatusActive,
 		LastUsed:        time.Now(),
@@ -595,15 +838,33 @@ func (sm *SessionManager) AddSession(sessionInfo SessionInfo) {
 	
 	// 只有在启用熔断器时才初始化熔断器
 	if sm.config.CircuitBreakerEnabled {
-		sessionHealth.CircuitBreaker = &CircuitBreaker{
-			State:     CircuitClosed,
-			Threshold: 5,
-			Timeout:   60 * time.Second,
-		}
+		sessionHealth.CircuitBreaker = newCircuitBreaker(sm.config)
 	}
 	
 	// 添加到sessions映射中
 	sm.sessions[sessionInfo.SessionKey] = sessionHealth
+	sm.clients.add(sessionInfo.SessionKey)
+
+	// 唤醒等待队列
+	sm.queue.notify()
+}
+
+// UpdateSession 更新session的orgID、路由属性与并发上限，健康状态保持不变
+func (sm *SessionManager) UpdateSession(sessionInfo SessionInfo) error {
+	_, err := sm.withSession(sessionInfo.SessionKey, func(session *SessionHealth) error {
+		session.OrgID = sessionInfo.OrgID
+		session.setAttributes(sessionInfo)
+		return nil
+	})
+	return err
+}
+
+// setAttributes 复制配置中的标签、层级、可用模型与并发上限，调用方需持有session锁
+func (s *SessionHealth) setAttributes(info SessionInfo) {
+	s.Tags = append([]string(nil), info.Tags...)
+	s.Tier = info.Tier
+	s.AllowedModels = append([]string(nil), info.AllowedModels...)
+	s.MaxConcurrent = info.MaxConcurrent
 }
 
 // RemoveSession 从SessionManager中移除session
@@ -613,12 +874,125 @@ func (sm *SessionManager) RemoveSession(sessionKey string) {
 
 	// 从sessions映射中删除
 	delete(sm.sessions, sessionKey)
+	sm.clients.remove(sessionKey)
+}
+
+// SyncSessions 让session集合与配置一致：添加新增的、移除已删除的，保留其余session的健康状态
+// 返回新增与移除的session key
+func (sm *SessionManager) SyncSessions(sessions []SessionInfo) (added, removed []string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	wanted := make(map[string]bool, len(sessions))
+	for _, info := range sessions {
+		wanted[info.SessionKey] = true
+		session, exists := sm.sessions[info.SessionKey]
+		if !exists {
+			sm.addSessionLocked(info)
+			added = append(added, info.SessionKey)
+			continue
+		}
+		session.mu.Lock()
+		if info.OrgID != "" {
+			session.OrgID = info.OrgID
+		}
+		session.setAttributes(info)
+		session.mu.Unlock()
+	}
+	for key := range sm.sessions {
+		if !wanted[key] {
+			delete(sm.sessions, key)
+			sm.clients.remove(key)
+			removed = append(removed, key)
+		}
+	}
+	// 并发上限可能已调高
+	sm.queue.notify()
+	return added, removed
+}
+
+// ApplyConfig 在线应用新配置：调度策略、冷却时间表、熔断阈值等在写锁内一次切换
+// session的健康状态与统计保留，进行中的冷却不受影响；状态存储只在创建时生效
+func (sm *SessionManager) ApplyConfig(config SessionManagerConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	config = withDefaults(config)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	config.StateStore = sm.config.StateStore
+	strategyChanged := config.ScheduleStrategy != sm.config.ScheduleStrategy
+	sm.config = config
+	if strategyChanged {
+		sm.scheduler = sm.createScheduler()
+	}
+
+	for _, session := range sm.sessions {
+		session.mu.Lock()
+		switch {
+		case !config.CircuitBreakerEnabled:
+			// 关闭熔断器时释放已熔断的session
+			session.CircuitBreaker = nil
+			if session.Status == StatusCircuitOpen {
+				session.Status = StatusActive
+				sm.restoreHealthScore(session)
+			}
+		case session.CircuitBreaker == nil:
+			session.CircuitBreaker = newCircuitBreaker(config)
+		default:
+			session.CircuitBreaker.Threshold = config.CircuitBreakerThreshold
+			session.CircuitBreaker.Timeout = config.CircuitBreakerTimeout
+		}
+		session.mu.Unlock()
+	}
+
+	// 新配置可能让等待中的请求有了可用session
+	sm.queue.notify()
+	return nil
+}
+
+// GetConfig 获取当前生效的配置（已填充默认值）
+func (sm *SessionManager) GetConfig() SessionManagerConfig {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.config
+}
+
+// GetClient 获取session在连接池中的上游客户端，session不存在或未注册创建函数时返回nil
+// proxy 与连接池当前代理不一致时重建全部客户端
+func (sm *SessionManager) GetClient(sessionKey string, proxy string) UpstreamClient {
+	sm.mu.RLock()
+	_, exists := sm.sessions[sessionKey]
+	sm.mu.RUnlock()
+	if !exists {
+		return nil
+	}
+	return sm.clients.get(sessionKey, proxy)
+}
+
+// SetClientProxy 设置连接池使用的代理，并为所有session创建客户端
+func (sm *SessionManager) SetClientProxy(proxy string) {
+	sm.mu.RLock()
+	keys := make([]string, 0, len(sm.sessions))
+	for key := range sm.sessions {
+		keys = append(keys, key)
+	}
+	sm.mu.RUnlock()
+	sm.clients.setProxy(proxy, keys)
+}
+
+// GetClientPoolStats 获取连接池统计信息
+func (sm *SessionManager) GetClientPoolStats() ClientPoolStats {
+	return sm.clients.getStats()
 }
 
 // IsSessionAvailable 检查session是否可用
 func (sm *SessionManager) IsSessionAvailable(sessionKey string) bool {
 	sm.mu.RLock()
 	session, exists := sm.sessions[sessionKey]
+	minHealthScore := sm.config.MinHealthScore
 	sm.mu.RUnlock()
 
 	if !exists {
@@ -629,6 +1003,6 @@ func (sm *SessionManager) IsSessionAvailable(sessionKey string) bool {
 	defer session.mu.RUnlock()
 
 	return session.Status == StatusActive && 
-		   session.HealthScore >= sm.config.MinHealthScore &&
+		   session.HealthScore >= minHealthScore &&
 		   time.Now().After(session.CooldownUntil)
 }
\ No newline at end of file
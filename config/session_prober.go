@@ -0,0 +1,123 @@
+package config
+
+import (
+	"claude2api/logger"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultProbeConcurrency = 2
+
+// ProbeResult 一次主动探测的结果
+type ProbeResult struct {
+	OrgID         string
+	RateLimitTier string
+	Latency       time.Duration
+	// ErrorType 与 Err 仅在探测失败时设置
+	ErrorType ErrorType
+	Err       error
+}
+
+// SessionProber 支持主动探测的上游客户端，由 core 包实现
+// 探测只调用账户与组织等轻量接口，不消耗消息额度
+type SessionProber interface {
+	Probe(orgID string) *ProbeResult
+}
+
+// runProbes 按健康检查间隔定期探测所有session，未启用探测时只等待下一轮
+func (sm *SessionManager) runProbes() {
+	timer := time.NewTimer(sm.GetConfig().HealthCheckInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-sm.stopProbes:
+			return
+		}
+		config := sm.GetConfig()
+		if config.ProbeEnabled {
+			sm.probeSessions(config.ProbeConcurrency)
+		}
+		// 间隔可能已在线修改
+		timer.Reset(sm.GetConfig().HealthCheckInterval)
+	}
+}
+
+// probeSessions 并发探测所有session，同时进行的探测不超过 concurrency 个
+func (sm *SessionManager) probeSessions(concurrency int) {
+	// 先按时间恢复冷却或熔断结束的session
+	sm.performHealthCheck()
+
+	sm.mu.RLock()
+	keys := make([]string, 0, len(sm.sessions))
+	for key := range sm.sessions {
+		keys = append(keys, key)
+	}
+	sm.mu.RUnlock()
+
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(sessionKey string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			sm.probeSession(sessionKey)
+		}(key)
+	}
+	wg.Wait()
+}
+
+// probeSession 探测单个session，结果只影响健康度、冷却与熔断，不计入请求统计；成功时更新组织与限额层级
+func (sm *SessionManager) probeSession(sessionKey string) {
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionKey]
+	sm.mu.RUnlock()
+	if !exists {
+		return
+	}
+	prober, ok := sm.clients.lookup(sessionKey).(SessionProber)
+	if !ok {
+		return
+	}
+
+	session.mu.RLock()
+	orgID := session.OrgID
+	session.mu.RUnlock()
+
+	result := prober.Probe(orgID)
+	if result.Err != nil {
+		logger.Warn(fmt.Sprintf("Probe of session %s failed (%s): %v", logger.MaskSecret(sessionKey), result.ErrorType, result.Err))
+		sm.recordProbeError(sessionKey, result.ErrorType, result.Err)
+		return
+	}
+	sm.UpdateOrganization(sessionKey, result.OrgID, result.RateLimitTier)
+	sm.recordProbeSuccess(sessionKey)
+}
+
+// UpdateOrganization 记录探测或验证得到的组织与限额层级，orgID 变化时通知配置同步
+func (sm *SessionManager) UpdateOrganization(sessionKey, orgID, rateLimitTier string) {
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionKey]
+	onOrgIDChanged := sm.onOrgIDChanged
+	sm.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	session.mu.Lock()
+	changed := orgID != "" && orgID != session.OrgID
+	if changed {
+		session.OrgID = orgID
+	}
+	session.RateLimitTier = rateLimitTier
+	session.mu.Unlock()
+
+	if changed && onOrgIDChanged != nil {
+		onOrgIDChanged(sessionKey, orgID)
+	}
+}
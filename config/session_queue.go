@@ -0,0 +1,195 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoAvailableSessions 当前没有可用的session
+	ErrNoAvailableSessions = errors.New("no available sessions")
+	// ErrQueueFull 等待队列已满
+	ErrQueueFull = errors.New("session wait queue is full")
+	// ErrQueueTimeout 超过最长等待时间仍没有可用的session
+	ErrQueueTimeout = errors.New("timed out waiting for an available session")
+)
+
+const (
+	defaultQueueMaxWait = 30 * time.Second
+	defaultQueueMaxSize = 100
+)
+
+// sessionQueue 所有session都不可用时的等待队列
+type sessionQueue struct {
+	ready   chan struct{}
+	waiting int
+	mu      sync.Mutex
+}
+
+func newSessionQueue() *sessionQueue {
+	return &sessionQueue{
+		ready: make(chan struct{}),
+	}
+}
+
+// enter 进入队列，返回当前的唤醒通道；队列已满时返回 false
+func (q *sessionQueue) enter(maxSize int) (<-chan struct{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.waiting >= maxSize {
+		return nil, false
+	}
+	q.waiting++
+	return q.ready, true
+}
+
+// wait 返回当前的唤醒通道
+func (q *sessionQueue) wait() <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.ready
+}
+
+func (q *sessionQueue) leave() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.waiting--
+}
+
+// notify 唤醒所有等待者
+func (q *sessionQueue) notify() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.waiting == 0 {
+		return
+	}
+	close(q.ready)
+	q.ready = make(chan struct{})
+}
+
+func (q *sessionQueue) size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.waiting
+}
+
+// WaitForSession 选择最佳session并占用一个并发名额，所有session都不可用或已满时排队等待
+// session 冷却结束、熔断器半开、并发名额释放或管理员添加session时唤醒，超过 queueMaxWait 返回 ErrQueueTimeout
+// 调用方需在请求结束时调用 ReleaseSession
+func (sm *SessionManager) WaitForSession(ctx context.Context, routing *RoutingContext, excludeKeys []string) (*SessionHealth, error) {
+	config := sm.GetConfig()
+	session, err := sm.acquireBestSession(routing, excludeKeys)
+	if err == nil || !errors.Is(err, ErrNoAvailableSessions) || config.QueueMaxWait < 0 {
+		return session, err
+	}
+	if !sm.hasCandidate(routing, excludeKeys) {
+		return nil, err
+	}
+
+	ready, ok := sm.queue.enter(config.QueueMaxSize)
+	if !ok {
+		return nil, ErrQueueFull
+	}
+	defer sm.queue.leave()
+
+	deadline := time.NewTimer(config.QueueMaxWait)
+	defer deadline.Stop()
+
+	for {
+		// 在下一个session冷却结束时主动检查，不依赖健康检查周期
+		var recovery <-chan time.Time
+		var recoveryTimer *time.Timer
+		if next := sm.EarliestRecovery(); !next.IsZero() {
+			recoveryTimer = time.NewTimer(time.Until(next) + 10*time.Millisecond)
+			recovery = recoveryTimer.C
+		}
+
+		var waitErr error
+		select {
+		case <-ctx.Done():
+			waitErr = ctx.Err()
+		case <-deadline.C:
+			waitErr = ErrQueueTimeout
+		case <-ready:
+		case <-recovery:
+			sm.performHealthCheck()
+		}
+		if recoveryTimer != nil {
+			recoveryTimer.Stop()
+		}
+		if waitErr != nil {
+			return nil, waitErr
+		}
+
+		ready = sm.queue.wait()
+		session, err = sm.acquireBestSession(routing, excludeKeys)
+		if err == nil || !errors.Is(err, ErrNoAvailableSessions) {
+			return session, err
+		}
+	}
+}
+
+// acquireBestSession 选择最佳session并占用一个并发名额，名额被并发的请求抢先占满时改选其他session
+func (sm *SessionManager) acquireBestSession(routing *RoutingContext, excludeKeys []string) (*SessionHealth, error) {
+	for {
+		session, err := sm.SelectBestSession(routing, excludeKeys)
+		if err != nil {
+			return nil, err
+		}
+		if sm.AcquireSession(session.SessionKey) == nil {
+			return session, nil
+		}
+		excludeKeys = append(excludeKeys[:len(excludeKeys):len(excludeKeys)], session.SessionKey)
+	}
+}
+
+// hasCandidate 是否存在符合路由条件、未被排除且未停用的session，全部被排除或停用时排队没有意义
+func (sm *SessionManager) hasCandidate(routing *RoutingContext, excludeKeys []string) bool {
+	excluded := make(map[string]bool, len(excludeKeys))
+	for _, key := range excludeKeys {
+		excluded[key] = true
+	}
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, candidates := range sm.routingStages(routing) {
+		for key, session := range candidates {
+			session.mu.RLock()
+			held := session.isHeld()
+			session.mu.RUnlock()
+			if !excluded[key] && !held {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EarliestRecovery 返回最早结束冷却或熔断的时间，没有等待恢复的session时返回零值
+func (sm *SessionManager) EarliestRecovery() time.Time {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	now := time.Now()
+	var earliest time.Time
+	consider := func(t time.Time) {
+		if t.After(now) && (earliest.IsZero() || t.Before(earliest)) {
+			earliest = t
+		}
+	}
+	for _, session := range sm.sessions {
+		session.mu.RLock()
+		consider(session.CooldownUntil)
+		if session.Status == StatusCircuitOpen && session.CircuitBreaker != nil {
+			consider(session.CircuitBreaker.NextAttempt)
+		}
+		session.mu.RUnlock()
+	}
+	return earliest
+}
+
+// GetQueueLength 获取等待队列中的请求数
+func (sm *SessionManager) GetQueueLength() int {
+	return sm.queue.size()
+}
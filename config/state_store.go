@@ -0,0 +1,222 @@
+package config
+
+import (
+	"claude2api/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// StateStoreConfig SessionManager 状态持久化配置
+type StateStoreConfig struct {
+	Type             string        `yaml:"type"` // "file"，为空表示不持久化
+	Path             string        `yaml:"path"`
+	SnapshotInterval time.Duration `yaml:"snapshotInterval"`
+}
+
+const (
+	defaultStateFilePath         = "data/session_state.json"
+	defaultStateSnapshotInterval = time.Minute
+)
+
+// ManagerState SessionManager 的持久化快照
+type ManagerState struct {
+	SavedAt  time.Time        `json:"saved_at"`
+	Sessions []*SessionHealth `json:"sessions"`
+	Stats    *ManagerStats    `json:"stats"`
+}
+
+// StateStore SessionManager 状态存储
+type StateStore interface {
+	// Load 读取最近一次快照，没有快照时返回 nil
+	Load() (*ManagerState, error)
+	// Save 保存快照
+	Save(state *ManagerState) error
+}
+
+// NewStateStore 根据配置创建状态存储，未配置时返回 nil
+func NewStateStore(cfg StateStoreConfig) (StateStore, error) {
+	switch cfg.Type {
+	case "":
+		return nil, nil
+	case "file":
+		path := cfg.Path
+		if path == "" {
+			path = defaultStateFilePath
+		}
+		return NewFileStateStore(path), nil
+	default:
+		return nil, fmt.Errorf("unknown state store type: %s", cfg.Type)
+	}
+}
+
+// FileStateStore 以 JSON 文件保存状态
+type FileStateStore struct {
+	path string
+}
+
+// NewFileStateStore creates a state store backed by a local JSON file
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+func (s *FileStateStore) Load() (*ManagerState, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state ManagerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+	return &state, nil
+}
+
+// Save 先写临时文件再重命名，避免进程中断时留下损坏的快照
+func (s *FileStateStore) Save(state *ManagerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, secretFileMode(s.path))
+}
+
+// initStateStore 创建状态存储，恢复上次保存的状态并启动定期快照
+func (sm *SessionManager) initStateStore() {
+	store, err := NewStateStore(sm.config.StateStore)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to create state store: %v", err))
+		return
+	}
+	if store == nil {
+		return
+	}
+	sm.store = store
+	sm.restoreState()
+
+	interval := sm.config.StateStore.SnapshotInterval
+	if interval <= 0 {
+		interval = defaultStateSnapshotInterval
+	}
+	sm.stopSnapshots = make(chan struct{})
+	go sm.runSnapshots(interval)
+}
+
+// restoreState 从状态存储恢复健康状态与统计，只恢复仍在配置中的session
+func (sm *SessionManager) restoreState() {
+	state, err := sm.store.Load()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to load session state: %v", err))
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	restored := 0
+	for _, saved := range state.Sessions {
+		session, exists := sm.sessions[saved.SessionKey]
+		if !exists {
+			continue
+		}
+		session.mu.Lock()
+		if session.OrgID == "" {
+			session.OrgID = saved.OrgID
+		}
+		session.HealthScore = saved.HealthScore
+		session.Status = saved.Status
+		// 重启后没有进行中的请求，排空中的session直接停用
+		if session.Status == StatusDraining {
+			session.Status = StatusDisabled
+		}
+		session.LastUsed = saved.LastUsed
+		session.LastError = saved.LastError
+		session.CooldownUntil = saved.CooldownUntil
+		session.ErrorCount = saved.ErrorCount
+		session.SuccessCount = saved.SuccessCount
+		session.TotalRequests = saved.TotalRequests
+		session.AvgResponseTime = saved.AvgResponseTime
+		if saved.ErrorTypes != nil {
+			session.ErrorTypes = saved.ErrorTypes
+		}
+		if saved.RecentErrors != nil {
+			session.RecentErrors = saved.RecentErrors
+		}
+		session.Weights = saved.Weights
+		if saved.CircuitBreaker != nil && sm.config.CircuitBreakerEnabled {
+			session.CircuitBreaker = saved.CircuitBreaker
+		}
+		session.RateLimitType = saved.RateLimitType
+		session.RateLimitResetsAt = saved.RateLimitResetsAt
+		session.RateLimitTier = saved.RateLimitTier
+		session.mu.Unlock()
+		restored++
+	}
+
+	if saved := state.Stats; saved != nil {
+		sm.stats.mu.Lock()
+		sm.stats.TotalRequests = saved.TotalRequests
+		sm.stats.SuccessfulReqs = saved.SuccessfulReqs
+		sm.stats.FailedRequests = saved.FailedRequests
+		sm.stats.AverageLatency = saved.AverageLatency
+		if saved.ErrorsByType != nil {
+			sm.stats.ErrorsByType = saved.ErrorsByType
+		}
+		sm.stats.LastReset = saved.LastReset
+		if saved.CallRecords != nil {
+			sm.stats.CallRecords = saved.CallRecords
+		}
+		if saved.CallCountByHour != nil {
+			sm.stats.CallCountByHour = saved.CallCountByHour
+		}
+		sm.stats.mu.Unlock()
+	}
+
+	logger.Info(fmt.Sprintf("Restored state of %d sessions saved at %s", restored, state.SavedAt.Format(time.RFC3339)))
+}
+
+// runSnapshots 定期保存状态快照
+func (sm *SessionManager) runSnapshots(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := sm.SaveState(); err != nil {
+				logger.Error(fmt.Sprintf("Failed to save session state: %v", err))
+			}
+		case <-sm.stopSnapshots:
+			return
+		}
+	}
+}
+
+// SaveState 保存当前状态快照，未配置状态存储时不做处理
+func (sm *SessionManager) SaveState() error {
+	if sm.store == nil {
+		return nil
+	}
+	return sm.store.Save(&ManagerState{
+		SavedAt:  time.Now(),
+		Sessions: sm.GetSessionsHealth(),
+		Stats:    sm.GetStats(),
+	})
+}
+
+// Close 停止定期快照并保存最终状态，在服务关闭时调用
+func (sm *SessionManager) Close() error {
+	sm.closeOnce.Do(func() {
+		if sm.stopSnapshots != nil {
+			close(sm.stopSnapshots)
+		}
+		if sm.stopProbes != nil {
+			close(sm.stopProbes)
+		}
+	})
+	return sm.SaveState()
+}
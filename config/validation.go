@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// 可用的调度策略
+var scheduleStrategies = []string{"round_robin", "health_priority", "weighted", "adaptive", "least_connections"}
+
+// IsValidScheduleStrategy 检查调度策略名称是否有效
+func IsValidScheduleStrategy(strategy string) bool {
+	for _, name := range scheduleStrategies {
+		if strategy == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldError 单个配置项的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError 配置校验错误，列出所有不合法的配置项
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		parts = append(parts, field.Field+": "+field.Message)
+	}
+	return "invalid configuration: " + strings.Join(parts, "; ")
+}
+
+// Add 记录一个配置项的错误
+func (e *ValidationError) Add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err 没有错误时返回 nil
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate 校验SessionManager配置，零值表示使用默认值
+func (cfg SessionManagerConfig) Validate() error {
+	errs := &ValidationError{}
+	cfg.validate(errs)
+	return errs.Err()
+}
+
+func (cfg SessionManagerConfig) validate(errs *ValidationError) {
+	if cfg.ScheduleStrategy != "" && !IsValidScheduleStrategy(cfg.ScheduleStrategy) {
+		errs.Add("sessionManager.scheduleStrategy", "must be one of %s", strings.Join(scheduleStrategies, ", "))
+	}
+	if cfg.HealthCheckInterval < 0 {
+		errs.Add("sessionManager.healthCheckInterval", "must be non-negative")
+	}
+	if cfg.MinHealthScore < 0 || cfg.MinHealthScore > 1 {
+		errs.Add("sessionManager.minHealthScore", "must be between 0 and 1")
+	}
+	if cfg.MaxRetryAttempts < 0 {
+		errs.Add("sessionManager.maxRetryAttempts", "must be non-negative")
+	}
+	if cfg.CircuitBreakerThreshold < 0 {
+		errs.Add("sessionManager.circuitBreakerThreshold", "must be non-negative")
+	}
+	if cfg.CircuitBreakerTimeout < 0 {
+		errs.Add("sessionManager.circuitBreakerTimeout", "must be non-negative")
+	}
+	for name, period := range cfg.CooldownPeriods {
+		if !isValidErrorTypeName(name) {
+			errs.Add("sessionManager.cooldownPeriods."+name, "unknown error type")
+		} else if period < 0 {
+			errs.Add("sessionManager.cooldownPeriods."+name, "must be non-negative")
+		}
+	}
+	if cfg.QueueMaxSize < 0 {
+		errs.Add("sessionManager.queueMaxSize", "must be non-negative")
+	}
+	if cfg.ProbeConcurrency < 0 {
+		errs.Add("sessionManager.probeConcurrency", "must be non-negative")
+	}
+	if cfg.MaxConcurrent < 0 {
+		errs.Add("sessionManager.maxConcurrent", "must be non-negative")
+	}
+	for i, rule := range cfg.Routing {
+		field := fmt.Sprintf("sessionManager.routing[%d]", i)
+		if !isValidModelPattern(rule.Model) {
+			errs.Add(field+".model", "must be a model name or pattern such as claude-opus-*")
+		}
+		for j, apiKey := range rule.APIKeys {
+			if strings.TrimSpace(apiKey) == "" {
+				errs.Add(fmt.Sprintf("%s.apiKeys[%d]", field, j), "must not be empty")
+			}
+		}
+		if len(rule.Groups) == 0 {
+			errs.Add(field+".groups", "must list at least one session group")
+		}
+	}
+	if cfg.StateStore.Type != "" && cfg.StateStore.Type != "file" {
+		errs.Add("sessionManager.stateStore.type", "must be empty or file")
+	}
+}
+
+// Validate 校验session的路由属性与并发上限
+func (s SessionInfo) Validate() error {
+	errs := &ValidationError{}
+	s.validate(errs, "")
+	return errs.Err()
+}
+
+func (s SessionInfo) validate(errs *ValidationError, prefix string) {
+	for _, pattern := range s.AllowedModels {
+		if !isValidModelPattern(pattern) {
+			errs.Add(prefix+"allowedModels", "invalid model pattern %q", pattern)
+		}
+	}
+	if s.MaxConcurrent < 0 {
+		errs.Add(prefix+"maxConcurrent", "must be non-negative")
+	}
+}
+
+// isValidModelPattern 检查模型名的通配模式
+func isValidModelPattern(pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+// isValidErrorTypeName 检查冷却时间表中的错误类型名称
+func isValidErrorTypeName(name string) bool {
+	for _, errorType := range []ErrorType{ErrorRateLimit, ErrorAuth, ErrorServer, ErrorNetwork, ErrorTimeout, ErrorOther} {
+		if name == errorType.String() {
+			return true
+		}
+	}
+	return false
+}
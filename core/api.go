@@ -2,8 +2,10 @@ package core
 
 import (
 	"bufio"
+	"claude2api/config"
 	"claude2api/logger"
 	"claude2api/model"
+	"claude2api/utils"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -12,6 +14,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,17 +22,21 @@ import (
 	"github.com/imroc/req/v3"
 )
 
+// Client 封装单个 sessionKey 的 claude.ai 请求，不保存请求级状态，可被并发请求共享
+// 每次发送的参数通过 MessageRequestBuilder 构建
 type Client struct {
-	SessionKey   string
-	orgID        string
-	client       *req.Client
-	model        string
-	defaultAttrs map[string]interface{}
+	SessionKey string
+	orgID      string
+	client     *req.Client
+	mu         sync.RWMutex
 }
 
 type ResponseEvent struct {
 	Type         string `json:"type"`
 	Index        int    `json:"index"`
+	Message      struct {
+		UUID string `json:"uuid"`
+	} `json:"message"`
 	ContentBlock struct {
 		Type string `json:"type"`
 	} `json:"content_block"`
@@ -43,9 +50,10 @@ type ResponseEvent struct {
 	Error struct {
 		Message string `json:"message"`
 	} `json:"error"`
+	MessageLimit messageLimit `json:"message_limit"`
 }
 
-func NewClient(sessionKey string, proxy string, model string) *Client {
+func NewClient(sessionKey string, proxy string) *Client {
 	client := req.C().ImpersonateChrome().SetTimeout(time.Minute * 5)
 	client.Transport.SetResponseHeaderTimeout(time.Second * 10)
 	if proxy != "" {
@@ -72,104 +80,107 @@ func NewClient(sessionKey string, proxy string, model string) *Client {
 	c := &Client{
 		SessionKey: sessionKey,
 		client:     client,
-		model:      model,
-		defaultAttrs: map[string]interface{}{
-			"personalized_styles": []map[string]interface{}{
-				{
-					"type":       "default",
-					"key":        "Default",
-					"name":       "Normal",
-					"nameKey":    "normal_style_name",
-					"prompt":     "Normal",
-					"summary":    "Default responses from Claude",
-					"summaryKey": "normal_style_summary",
-					"isDefault":  true,
-				},
-			},
-			"tools": []map[string]interface{}{
-				{
-					"type": "web_search_v0",
-					"name": "web_search",
-				},
-				{"type": "artifacts_v0", "name": "artifacts"},
-				{"type": "repl_v0", "name": "repl"},
-			},
-			"parent_message_uuid": "00000000-0000-4000-8000-000000000000",
-			"attachments":         []interface{}{},
-			"files":               []interface{}{},
-			"sync_sources":        []interface{}{},
-			"rendering_mode":      "messages",
-			"timezone":            "America/Los_Angeles",
-		},
 	}
 	return c
 }
 
+func init() {
+	// 智能Session管理器按session缓存客户端，复用连接
+	config.RegisterClientFactory(func(sessionKey string, proxy string) config.UpstreamClient {
+		return NewClient(sessionKey, proxy)
+	})
+}
+
+// Close releases the idle upstream connections held by the client
+func (c *Client) Close() {
+	c.client.Transport.CloseIdleConnections()
+}
+
 // SetOrgID sets the organization ID for the client
 func (c *Client) SetOrgID(orgID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.orgID = orgID
 }
+
+// OrgID returns the organization ID set on the client
+func (c *Client) OrgID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.orgID
+}
+
 func (c *Client) GetOrgID() (string, error) {
+	orgs, _, err := c.listOrganizations()
+	if err != nil {
+		return "", err
+	}
+	org, err := selectOrganization(orgs)
+	if err != nil {
+		return "", err
+	}
+	return org.UUID, nil
+}
+
+// organization claude.ai 组织信息
+type organization struct {
+	ID            int    `json:"id"`
+	UUID          string `json:"uuid"`
+	Name          string `json:"name"`
+	RateLimitTier string `json:"rate_limit_tier"`
+}
+
+// listOrganizations 获取session所属的组织列表，同时返回状态码用于错误分类
+func (c *Client) listOrganizations() ([]organization, int, error) {
 	url := "https://claude.ai/api/organizations"
 	resp, err := c.client.R().
 		SetHeader("referer", "https://claude.ai/new").
 		Get(url)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, 0, fmt.Errorf("request failed: %w", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-	type OrgResponse []struct {
-		ID            int    `json:"id"`
-		UUID          string `json:"uuid"`
-		Name          string `json:"name"`
-		RateLimitTier string `json:"rate_limit_tier"`
+		return nil, resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	var orgs OrgResponse
+	var orgs []organization
 	if err := json.Unmarshal(resp.Bytes(), &orgs); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return nil, resp.StatusCode, fmt.Errorf("failed to parse response: %w", err)
 	}
+	return orgs, resp.StatusCode, nil
+}
+
+// selectOrganization 只有一个组织时直接使用，否则选择默认层级的组织
+func selectOrganization(orgs []organization) (*organization, error) {
 	if len(orgs) == 0 {
-		return "", errors.New("no organizations found")
+		return nil, errors.New("no organizations found")
 	}
 	if len(orgs) == 1 {
-		return orgs[0].UUID, nil
+		return &orgs[0], nil
 	}
-	for _, org := range orgs {
+	for i, org := range orgs {
 		if org.RateLimitTier == "default_claude_ai" || org.RateLimitTier == "default_claude_max_20x" || org.RateLimitTier == "default_raven_enterprise" {
-			return org.UUID, nil
+			return &orgs[i], nil
 		}
 	}
-	return "", errors.New("no default organization found")
-
+	return nil, errors.New("no default organization found")
 }
 
-// CreateConversation creates a new conversation and returns its UUID
-func (c *Client) CreateConversation() (string, error) {
-	if c.orgID == "" {
+// CreateConversation creates a new conversation for the model and returns its UUID
+func (c *Client) CreateConversation(model string) (string, error) {
+	orgID := c.OrgID()
+	if orgID == "" {
 		return "", errors.New("organization ID not set")
 	}
-	url := fmt.Sprintf("https://claude.ai/api/organizations/%s/chat_conversations", c.orgID)
-	// 如果以-think结尾
-	if strings.HasSuffix(c.model, "-think") {
-		c.model = strings.TrimSuffix(c.model, "-think")
-		if err := c.UpdateUserSetting("paprika_mode", "extended"); err != nil {
-			logger.Error(fmt.Sprintf("Failed to update paprika_mode: %v", err))
-		}
-	} else {
-		if err := c.UpdateUserSetting("paprika_mode", nil); err != nil {
-			logger.Error(fmt.Sprintf("Failed to update paprika_mode: %v", err))
-		}
-	}
+	url := fmt.Sprintf("https://claude.ai/api/organizations/%s/chat_conversations", orgID)
+	model = c.ApplyModelSetting(model)
 	requestBody := map[string]interface{}{
-		"model":                            c.model,
+		"model":                            model,
 		"uuid":                             uuid.New().String(),
 		"name":                             "",
 		"include_conversation_preferences": true,
 	}
-	if c.model == "claude-sonnet-4-20250514" {
+	if model == "claude-sonnet-4-20250514" {
 		// 删除model
 		delete(requestBody, "model")
 	}
@@ -197,19 +208,66 @@ func (c *Client) CreateConversation() (string, error) {
 	return uuid, nil
 }
 
+// ApplyModelSetting 根据模型后缀切换账号的扩展思考设置，返回发往 claude.ai 的模型名
+// 在已有会话中续写时需要在 SendMessage 前单独调用
+// paprika_mode 是账号级设置，同一账号上 -think 与普通模型的并发请求仍会互相影响
+func (c *Client) ApplyModelSetting(model string) string {
+	// 如果以-think结尾
+	if strings.HasSuffix(model, "-think") {
+		model = strings.TrimSuffix(model, "-think")
+		if err := c.UpdateUserSetting("paprika_mode", "extended"); err != nil {
+			logger.Error(fmt.Sprintf("Failed to update paprika_mode: %v", err))
+		}
+	} else {
+		if err := c.UpdateUserSetting("paprika_mode", nil); err != nil {
+			logger.Error(fmt.Sprintf("Failed to update paprika_mode: %v", err))
+		}
+	}
+	return model
+}
+
+// GetLatestMessageUUID returns the UUID of the newest message in a conversation
+func (c *Client) GetLatestMessageUUID(conversationID string) (string, error) {
+	orgID := c.OrgID()
+	if orgID == "" {
+		return "", errors.New("organization ID not set")
+	}
+	url := fmt.Sprintf("https://claude.ai/api/organizations/%s/chat_conversations/%s?tree=False&rendering_mode=messages",
+		orgID, conversationID)
+	resp, err := c.client.R().
+		SetHeader("referer", fmt.Sprintf("https://claude.ai/chat/%s", conversationID)).
+		Get(url)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var result struct {
+		ChatMessages []struct {
+			UUID   string `json:"uuid"`
+			Sender string `json:"sender"`
+		} `json:"chat_messages"`
+	}
+	if err := json.Unmarshal(resp.Bytes(), &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.ChatMessages) == 0 {
+		return "", errors.New("conversation has no messages")
+	}
+	return result.ChatMessages[len(result.ChatMessages)-1].UUID, nil
+}
+
 // SendMessage sends a message to a conversation and returns the status and response
-func (c *Client) SendMessage(conversationID string, message string, stream bool, gc *gin.Context) (int, error) {
-	if c.orgID == "" {
+func (c *Client) SendMessage(conversationID string, message *MessageRequest, stream bool, gc *gin.Context) (int, error) {
+	orgID := c.OrgID()
+	if orgID == "" {
 		return 500, errors.New("organization ID not set")
 	}
 	url := fmt.Sprintf("https://claude.ai/api/organizations/%s/chat_conversations/%s/completion",
-		c.orgID, conversationID)
-	// Create request body with default attributes
-	requestBody := c.defaultAttrs
-	requestBody["prompt"] = message
-	if c.model != "claude-sonnet-4-20250514" {
-		requestBody["model"] = c.model
-	}
+		orgID, conversationID)
+	// 每次请求独立生成请求体
+	requestBody := message.Body()
 	// Set up streaming response
 	resp, err := c.client.R().DisableAutoReadResponse().
 		SetHeader("referer", fmt.Sprintf("https://claude.ai/chat/%s", conversationID)).
@@ -223,36 +281,44 @@ func (c *Client) SendMessage(conversationID string, message string, stream bool,
 	}
 	logger.Info(fmt.Sprintf("Claude response status code: %d", resp.StatusCode))
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return http.StatusTooManyRequests, fmt.Errorf("rate limit exceeded")
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return http.StatusTooManyRequests, parseRateLimitResponse(resp.Header, body)
 	}
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 	return 200, c.HandleResponse(resp.Body, stream, gc)
 }
 
-// HandleResponse converts Claude's SSE format to OpenAI format and writes to the response writer
+// HandleResponse converts Claude's SSE format to the requested API format (OpenAI by default) and writes to the response writer
 func (c *Client) HandleResponse(body io.ReadCloser, stream bool, gc *gin.Context) error {
 	defer body.Close()
 	// Set headers for streaming
-	if stream {
-		gc.Writer.Header().Set("Content-Type", "text/event-stream")
-		gc.Writer.Header().Set("Cache-Control", "no-cache")
-		gc.Writer.Header().Set("Connection", "keep-alive")
-		// 发送200状态码
-		gc.Writer.WriteHeader(http.StatusOK)
-		gc.Writer.Flush()
+	// bufferFirstToken 模式下响应头随第一个增量发送，此前失败仍可重试
+	if stream && !config.ConfigInstance.BufferFirstToken {
+		model.StartStream(gc)
 	}
 	scanner := bufio.NewScanner(body)
 	clientDone := gc.Request.Context().Done()
-	// Keep track of the full response for the final message
+	// 累积思考内容与工具调用参数，与实际输出的正文一起估算 completion token
+	writer := &usageCountingWriter{ResponseWriter: model.NewResponseWriter(gc, stream)}
+	gc.Set("AssistantMessageUUID", "")
+	gc.Set("MessageLimit", nil)
+	// 请求携带 tools 时，从回复文本中解析工具调用
+	var toolParser *ToolCallParser
+	if gc.GetBool("ToolsEnabled") {
+		toolParser = NewToolCallParser()
+	}
 	thinkingShown := false
-	res_all_text := ""
+	thinkingMode := config.ConfigInstance.GetThinkingMode(gc.GetString("ThinkingMode"))
 	partial_json_shown := false
 	useTool := false
 	useToolEnd := false
 	nextLanguage := false
 	languageStr := "md"
+loop:
 	for scanner.Scan() {
 		select {
 		case <-clientDone:
@@ -271,8 +337,23 @@ func (c *Client) HandleResponse(body io.ReadCloser, stream bool, gc *gin.Context
 		var event ResponseEvent
 		if err := json.Unmarshal([]byte(data), &event); err == nil {
 			if event.Type == "error" && event.Error.Message != "" {
-				model.ReturnOpenAIResponse(event.Error.Message, stream, gc)
-				return nil
+				return writer.fail(fmt.Errorf("upstream error: %s", event.Error.Message))
+			}
+			// 流中的限额事件：记录限额类型与重置时间，尚未产生输出时按限流失败处理
+			if event.Type == "message_limit" && event.MessageLimit.Type != "" {
+				limit := &utils.RateLimitError{
+					LimitType: event.MessageLimit.Type,
+					ResetsAt:  event.MessageLimit.resetTime(),
+				}
+				if limit.LimitType == "exceeded_limit" && writer.completion.Len() == 0 && writer.Text() == "" {
+					return writer.fail(limit)
+				}
+				gc.Set("MessageLimit", limit)
+				continue
+			}
+			// 记录 assistant 消息 UUID，会话亲和模式下作为下一轮的 parent_message_uuid
+			if event.Type == "message_start" && event.Message.UUID != "" {
+				gc.Set("AssistantMessageUUID", event.Message.UUID)
 			}
 			if event.ContentBlock.Type == "tool_use" {
 				useTool = true
@@ -290,33 +371,40 @@ func (c *Client) HandleResponse(body io.ReadCloser, stream bool, gc *gin.Context
 					res_text = "\n```\n"
 					partial_json_shown = false
 				}
-				res_all_text += res_text
-				if !stream {
-					continue
+				if err := writer.WriteText(res_text); errors.Is(err, model.ErrGenerationStopped) {
+					break loop
 				}
-				model.ReturnOpenAIResponse(res_text, stream, gc)
 				continue
 			}
 			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
 				res_text := event.Delta.Text
-				res_all_text += res_text
-				if !stream {
-					continue
+				if toolParser != nil {
+					res_text = toolParser.Feed(res_text)
+					if res_text == "" {
+						continue
+					}
+				}
+				if err := writer.WriteText(res_text); errors.Is(err, model.ErrGenerationStopped) {
+					break loop
 				}
-				model.ReturnOpenAIResponse(res_text, stream, gc)
 				continue
 			}
 			if event.Delta.Type == "thinking_delta" {
+				switch thinkingMode {
+				case config.ThinkingModeDrop:
+					continue
+				case config.ThinkingModeReasoning:
+					writer.WriteReasoning(event.Delta.THINKING)
+					continue
+				}
 				res_text := event.Delta.THINKING
 				if !thinkingShown {
 					res_text = "<think> " + res_text
 					thinkingShown = true
 				}
-				res_all_text += res_text
-				if !stream {
-					continue
+				if err := writer.WriteText(res_text); errors.Is(err, model.ErrGenerationStopped) {
+					break loop
 				}
-				model.ReturnOpenAIResponse(res_text, stream, gc)
 				continue
 			}
 			if event.Delta.Type == "input_json_delta" {
@@ -378,27 +466,68 @@ func (c *Client) HandleResponse(body io.ReadCloser, stream bool, gc *gin.Context
 					res_text = "\n```" + languageStr + "\n" + res_text
 					partial_json_shown = true
 				}
-				res_all_text += res_text
-				if !stream {
-					continue
+				if err := writer.WriteText(res_text); errors.Is(err, model.ErrGenerationStopped) {
+					break loop
 				}
-				model.ReturnOpenAIResponse(res_text, stream, gc)
 				continue
 			}
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading response: %w", err)
+		return writer.fail(fmt.Errorf("error reading response: %w", err))
 	}
-	if !stream {
-		model.ReturnOpenAIResponse(res_all_text, stream, gc)
-	} else {
-		// 发送结束标志
-		gc.Writer.Write([]byte("data: [DONE]\n\n"))
-		gc.Writer.Flush()
+	if toolParser != nil {
+		if rest := toolParser.Flush(); rest != "" {
+			writer.WriteText(rest)
+		}
+		if calls := toolParser.ToolCalls(); len(calls) > 0 {
+			writer.WriteToolCalls(calls)
+		}
 	}
+	if toolParser != nil {
+		gc.Set("ResponseToolCalls", toolParser.ToolCalls())
+	}
+	// 只统计实际输出给客户端的正文，stop_sequences 截断之后的部分不计入用量
+	responseText := writer.Text()
+	gc.Set("ResponseText", responseText)
+	promptTokens := gc.GetInt("PromptTokens")
+	completionTokens := utils.EstimateTokens(responseText + writer.completion.String())
+	gc.Set("CompletionTokens", completionTokens)
+	writer.SetUsage(model.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	})
+	return writer.Finish()
+}
 
-	return nil
+// usageCountingWriter 记录写出的思考内容与工具调用参数，正文由 Text 返回
+type usageCountingWriter struct {
+	model.ResponseWriter
+	completion strings.Builder
+}
+
+// fail 处理上游中途失败：尚未写出数据时返回原错误以便重试，
+// 已写出数据时在响应中写入错误并结束，返回 ErrResponseCommitted 阻止重试
+func (w *usageCountingWriter) fail(err error) error {
+	if !w.Committed() {
+		return err
+	}
+	logger.Error(fmt.Sprintf("Upstream failed after response was committed: %v", err))
+	w.WriteError(err.Error())
+	return fmt.Errorf("%w: %w", model.ErrResponseCommitted, err)
+}
+
+func (w *usageCountingWriter) WriteReasoning(text string) error {
+	w.completion.WriteString(text)
+	return w.ResponseWriter.WriteReasoning(text)
+}
+
+func (w *usageCountingWriter) WriteToolCalls(calls []model.ToolCall) error {
+	for _, call := range calls {
+		w.completion.WriteString(call.Function.Name + call.Function.Arguments)
+	}
+	return w.ResponseWriter.WriteToolCalls(calls)
 }
 func decodeUnicodeEscape(s string) string {
 	var result []rune
@@ -425,11 +554,12 @@ func decodeUnicodeEscape(s string) string {
 
 // DeleteConversation deletes a conversation by ID
 func (c *Client) DeleteConversation(conversationID string) error {
-	if c.orgID == "" {
+	orgID := c.OrgID()
+	if orgID == "" {
 		return errors.New("organization ID not set")
 	}
 	url := fmt.Sprintf("https://claude.ai/api/organizations/%s/chat_conversations/%s",
-		c.orgID, conversationID)
+		orgID, conversationID)
 	requestBody := map[string]string{
 		"uuid": conversationID,
 	}
@@ -446,20 +576,18 @@ func (c *Client) DeleteConversation(conversationID string) error {
 	return nil
 }
 
-// UploadFile uploads files to Claude and adds them to the client's default attributes
+// UploadFile uploads files to Claude and returns their file UUIDs for MessageRequestBuilder.WithFiles
 // fileData should be in the format: data:image/jpeg;base64,/9j/4AA...
-func (c *Client) UploadFile(fileData []string) error {
-	if c.orgID == "" {
-		return errors.New("organization ID not set")
+func (c *Client) UploadFile(fileData []string) ([]string, error) {
+	orgID := c.OrgID()
+	if orgID == "" {
+		return nil, errors.New("organization ID not set")
 	}
 	if len(fileData) == 0 {
-		return errors.New("empty file data")
+		return nil, errors.New("empty file data")
 	}
 
-	// Initialize files array in default attributes if it doesn't exist
-	if _, ok := c.defaultAttrs["files"]; !ok {
-		c.defaultAttrs["files"] = []interface{}{}
-	}
+	var fileUUIDs []string
 
 	// Process each file
 	for _, fd := range fileData {
@@ -470,18 +598,18 @@ func (c *Client) UploadFile(fileData []string) error {
 		// Parse the base64 data
 		parts := strings.SplitN(fd, ",", 2)
 		if len(parts) != 2 {
-			return errors.New("invalid file data format")
+			return nil, errors.New("invalid file data format")
 		}
 
 		// Get the content type from the data URI
 		metaParts := strings.SplitN(parts[0], ":", 2)
 		if len(metaParts) != 2 {
-			return errors.New("invalid content type in file data")
+			return nil, errors.New("invalid content type in file data")
 		}
 
 		metaInfo := strings.SplitN(metaParts[1], ";", 2)
 		if len(metaInfo) != 2 || metaInfo[1] != "base64" {
-			return errors.New("invalid encoding in file data")
+			return nil, errors.New("invalid encoding in file data")
 		}
 
 		contentType := metaInfo[0]
@@ -489,7 +617,7 @@ func (c *Client) UploadFile(fileData []string) error {
 		// Decode the base64 data
 		fileBytes, err := base64.StdEncoding.DecodeString(parts[1])
 		if err != nil {
-			return fmt.Errorf("failed to decode base64 data: %w", err)
+			return nil, fmt.Errorf("failed to decode base64 data: %w", err)
 		}
 
 		// Determine filename based on content type
@@ -506,7 +634,7 @@ func (c *Client) UploadFile(fileData []string) error {
 		}
 
 		// Create the upload URL
-		url := fmt.Sprintf("https://claude.ai/api/%s/upload", c.orgID)
+		url := fmt.Sprintf("https://claude.ai/api/%s/upload", orgID)
 
 		// Create a multipart form request
 		resp, err := c.client.R().
@@ -517,11 +645,11 @@ func (c *Client) UploadFile(fileData []string) error {
 			Post(url)
 
 		if err != nil {
-			return fmt.Errorf("request failed: %w", err)
+			return nil, fmt.Errorf("request failed: %w", err)
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, resp.String())
+			return nil, fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, resp.String())
 		}
 
 		// Parse the response
@@ -530,30 +658,17 @@ func (c *Client) UploadFile(fileData []string) error {
 		}
 
 		if err := json.Unmarshal(resp.Bytes(), &result); err != nil {
-			return fmt.Errorf("failed to parse response: %w", err)
+			return nil, fmt.Errorf("failed to parse response: %w", err)
 		}
 
 		if result.FileUUID == "" {
-			return errors.New("file UUID not found in response")
+			return nil, errors.New("file UUID not found in response")
 		}
 
-		// Add file to default attributes
-		c.defaultAttrs["files"] = append(c.defaultAttrs["files"].([]interface{}), result.FileUUID)
-	}
-
-	return nil
-}
-
-func (c *Client) SetBigContext(context string) {
-	c.defaultAttrs["attachments"] = []map[string]interface{}{
-		{
-			"file_name":         "context.txt",
-			"file_type":         "text/plain",
-			"file_size":         len(context),
-			"extracted_content": context,
-		},
+		fileUUIDs = append(fileUUIDs, result.FileUUID)
 	}
 
+	return fileUUIDs, nil
 }
 
 // / UpdateUserSetting updates a single user setting on Claude.ai while preserving all other settings
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"strings"
+)
+
+// rootParentMessageUUID 新会话第一条消息的 parent_message_uuid
+const rootParentMessageUUID = "00000000-0000-4000-8000-000000000000"
+
+// MessageRequest 单次 completion 请求的参数，由 MessageRequestBuilder 构建后不可修改
+// Client 不再保存任何请求级状态，同一个 Client 可以被并发请求共享
+type MessageRequest struct {
+	prompt            string
+	model             string
+	parentMessageUUID string
+	files             []string
+	attachments       []map[string]interface{}
+	tools             []map[string]interface{}
+	style             map[string]interface{}
+	timezone          string
+}
+
+// MessageRequestBuilder 构建 MessageRequest
+type MessageRequestBuilder struct {
+	req MessageRequest
+}
+
+// NewMessageRequestBuilder creates a builder preloaded with the claude.ai web defaults
+func NewMessageRequestBuilder() *MessageRequestBuilder {
+	return &MessageRequestBuilder{
+		req: MessageRequest{
+			parentMessageUUID: rootParentMessageUUID,
+			tools:             defaultTools(),
+			style:             defaultStyle(),
+			timezone:          "America/Los_Angeles",
+		},
+	}
+}
+
+func (b *MessageRequestBuilder) WithPrompt(prompt string) *MessageRequestBuilder {
+	b.req.prompt = prompt
+	return b
+}
+
+// WithModel 设置模型，-think 后缀会在构建请求体时去掉
+func (b *MessageRequestBuilder) WithModel(model string) *MessageRequestBuilder {
+	b.req.model = model
+	return b
+}
+
+// WithParentMessageUUID 在已有会话中续写时指定上一条 assistant 消息
+func (b *MessageRequestBuilder) WithParentMessageUUID(uuid string) *MessageRequestBuilder {
+	b.req.parentMessageUUID = uuid
+	return b
+}
+
+// WithFiles 追加 UploadFile 返回的文件 UUID
+func (b *MessageRequestBuilder) WithFiles(fileUUIDs ...string) *MessageRequestBuilder {
+	b.req.files = append(b.req.files, fileUUIDs...)
+	return b
+}
+
+// WithAttachment 追加文本附件，例如 ContextAttachment 生成的大上下文
+func (b *MessageRequestBuilder) WithAttachment(attachment map[string]interface{}) *MessageRequestBuilder {
+	b.req.attachments = append(b.req.attachments, attachment)
+	return b
+}
+
+// WithTools 替换 claude.ai 内置工具列表
+func (b *MessageRequestBuilder) WithTools(tools []map[string]interface{}) *MessageRequestBuilder {
+	b.req.tools = tools
+	return b
+}
+
+func (b *MessageRequestBuilder) WithStyle(style map[string]interface{}) *MessageRequestBuilder {
+	b.req.style = style
+	return b
+}
+
+func (b *MessageRequestBuilder) WithTimezone(timezone string) *MessageRequestBuilder {
+	b.req.timezone = timezone
+	return b
+}
+
+// Build 返回请求的独立副本，之后对 builder 的修改不会影响已构建的请求
+func (b *MessageRequestBuilder) Build() *MessageRequest {
+	req := b.req
+	req.files = append([]string(nil), b.req.files...)
+	req.attachments = append([]map[string]interface{}(nil), b.req.attachments...)
+	req.tools = append([]map[string]interface{}(nil), b.req.tools...)
+	return &req
+}
+
+// Prompt returns the prompt text of the request
+func (r *MessageRequest) Prompt() string {
+	return r.prompt
+}
+
+// UpstreamModel returns the model name sent to claude.ai
+func (r *MessageRequest) UpstreamModel() string {
+	return strings.TrimSuffix(r.model, "-think")
+}
+
+// Body 生成 completion 请求体，每次调用返回新的 map
+func (r *MessageRequest) Body() map[string]interface{} {
+	files := make([]interface{}, 0, len(r.files))
+	for _, f := range r.files {
+		files = append(files, f)
+	}
+	attachments := make([]interface{}, 0, len(r.attachments))
+	for _, a := range r.attachments {
+		attachments = append(attachments, a)
+	}
+
+	body := map[string]interface{}{
+		"prompt":              r.prompt,
+		"personalized_styles": []map[string]interface{}{r.style},
+		"tools":               r.tools,
+		"parent_message_uuid": r.parentMessageUUID,
+		"attachments":         attachments,
+		"files":               files,
+		"sync_sources":        []interface{}{},
+		"rendering_mode":      "messages",
+		"timezone":            r.timezone,
+	}
+	if model := r.UpstreamModel(); model != "" && model != "claude-sonnet-4-20250514" {
+		body["model"] = model
+	}
+	return body
+}
+
+// ContextAttachment 把超长的历史作为 context.txt 附件发送
+func ContextAttachment(context string) map[string]interface{} {
+	return map[string]interface{}{
+		"file_name":         "context.txt",
+		"file_type":         "text/plain",
+		"file_size":         len(context),
+		"extracted_content": context,
+	}
+}
+
+func defaultStyle() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "default",
+		"key":        "Default",
+		"name":       "Normal",
+		"nameKey":    "normal_style_name",
+		"prompt":     "Normal",
+		"summary":    "Default responses from Claude",
+		"summaryKey": "normal_style_summary",
+		"isDefault":  true,
+	}
+}
+
+func defaultTools() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"type": "web_search_v0",
+			"name": "web_search",
+		},
+		{"type": "artifacts_v0", "name": "artifacts"},
+		{"type": "repl_v0", "name": "repl"},
+	}
+}
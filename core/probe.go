@@ -0,0 +1,63 @@
+package core
+
+import (
+	"claude2api/config"
+	"claude2api/utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Probe 通过账户与组织接口检查sessionKey是否仍然有效，不发送消息，不消耗额度
+// orgID 仍在组织列表中时保留，否则重新选择组织
+func (c *Client) Probe(orgID string) *config.ProbeResult {
+	startTime := time.Now()
+	result := &config.ProbeResult{}
+	fail := func(statusCode int, err error) *config.ProbeResult {
+		result.Latency = time.Since(startTime)
+		result.ErrorType = utils.ClassifyError(statusCode, err)
+		result.Err = err
+		return result
+	}
+
+	if statusCode, err := c.getAccount(); err != nil {
+		return fail(statusCode, err)
+	}
+	orgs, statusCode, err := c.listOrganizations()
+	if err != nil {
+		return fail(statusCode, err)
+	}
+
+	var org *organization
+	for i := range orgs {
+		if orgID != "" && orgs[i].UUID == orgID {
+			org = &orgs[i]
+			break
+		}
+	}
+	if org == nil {
+		if org, err = selectOrganization(orgs); err != nil {
+			return fail(statusCode, err)
+		}
+	}
+
+	result.Latency = time.Since(startTime)
+	result.OrgID = org.UUID
+	result.RateLimitTier = org.RateLimitTier
+	return result
+}
+
+// getAccount 获取当前账户信息，sessionKey过期或被吊销时返回 401/403
+func (c *Client) getAccount() (int, error) {
+	url := "https://claude.ai/api/account"
+	resp, err := c.client.R().
+		SetHeader("referer", "https://claude.ai/new").
+		Get(url)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"claude2api/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// messageLimit claude.ai 的限额信息，出现在 429 响应的 error.message 与流中的 message_limit 事件
+type messageLimit struct {
+	Type     string      `json:"type"`
+	ResetsAt json.Number `json:"resetsAt"`
+}
+
+// resetTime 解析 resetsAt（Unix 秒）
+func (l *messageLimit) resetTime() time.Time {
+	return parseUnixTime(l.ResetsAt.String())
+}
+
+// parseRateLimitResponse 从 429 响应的头和正文中解析限额类型与重置时间
+func parseRateLimitResponse(header http.Header, body []byte) *utils.RateLimitError {
+	rateLimitErr := &utils.RateLimitError{}
+
+	var payload struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		rateLimitErr.LimitType = payload.Error.Type
+		// error.message 本身是一段 JSON
+		var limit messageLimit
+		if err := json.Unmarshal([]byte(payload.Error.Message), &limit); err == nil {
+			if limit.Type != "" {
+				rateLimitErr.LimitType = limit.Type
+			}
+			rateLimitErr.ResetsAt = limit.resetTime()
+		} else {
+			rateLimitErr.Message = payload.Error.Message
+		}
+	}
+
+	if rateLimitErr.ResetsAt.IsZero() {
+		rateLimitErr.ResetsAt = parseUnixTime(header.Get("anthropic-ratelimit-unified-reset"))
+	}
+	if rateLimitErr.ResetsAt.IsZero() {
+		rateLimitErr.ResetsAt = parseRetryAfter(header.Get("retry-after"))
+	}
+	return rateLimitErr
+}
+
+// parseUnixTime 解析 Unix 秒时间戳，无效时返回零值
+func parseUnixTime(value string) time.Time {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
+
+// parseRetryAfter 解析 Retry-After（秒数或 HTTP 日期），无效时返回零值
+func parseRetryAfter(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return time.Time{}
+		}
+		return time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
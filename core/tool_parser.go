@@ -0,0 +1,133 @@
+package core
+
+import (
+	"claude2api/logger"
+	"claude2api/model"
+	"claude2api/utils"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ToolCallParser 从 Claude 的流式回复中分离出 <tool_call> 块
+// 第一个调用块之前的文本照常输出，之后的文本被丢弃
+type ToolCallParser struct {
+	buf      string
+	inCall   bool
+	seenCall bool
+	calls    []model.ToolCall
+}
+
+// NewToolCallParser creates a parser for prompt-level tool calls
+func NewToolCallParser() *ToolCallParser {
+	return &ToolCallParser{}
+}
+
+// Feed 写入一段回复增量，返回可以立即输出给客户端的文本
+func (p *ToolCallParser) Feed(text string) string {
+	p.buf += text
+	var out strings.Builder
+	for {
+		if p.inCall {
+			idx := strings.Index(p.buf, utils.ToolCallCloseTag)
+			if idx < 0 {
+				return out.String()
+			}
+			p.addCall(p.buf[:idx])
+			p.buf = p.buf[idx+len(utils.ToolCallCloseTag):]
+			p.inCall = false
+			continue
+		}
+
+		idx := strings.Index(p.buf, utils.ToolCallOpenTag)
+		if idx >= 0 {
+			if !p.seenCall {
+				out.WriteString(p.buf[:idx])
+			}
+			p.buf = p.buf[idx+len(utils.ToolCallOpenTag):]
+			p.inCall = true
+			p.seenCall = true
+			continue
+		}
+
+		// 保留可能是开始标记前缀的尾部，等待后续增量
+		keep := partialSuffixLen(p.buf, utils.ToolCallOpenTag)
+		if !p.seenCall {
+			out.WriteString(p.buf[:len(p.buf)-keep])
+		}
+		p.buf = p.buf[len(p.buf)-keep:]
+		return out.String()
+	}
+}
+
+// Flush 在回复结束时调用，返回剩余需要输出的文本
+func (p *ToolCallParser) Flush() string {
+	rest := p.buf
+	p.buf = ""
+	if p.inCall {
+		// 缺少结束标记时仍尝试解析
+		p.addCall(rest)
+		p.inCall = false
+		return ""
+	}
+	if p.seenCall {
+		return ""
+	}
+	return rest
+}
+
+// ToolCalls 返回已解析出的工具调用
+func (p *ToolCallParser) ToolCalls() []model.ToolCall {
+	return p.calls
+}
+
+func (p *ToolCallParser) addCall(raw string) {
+	var call struct {
+		Name       string          `json:"name"`
+		Arguments  json.RawMessage `json:"arguments"`
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &call); err != nil || call.Name == "" {
+		logger.Error(fmt.Sprintf("Failed to parse tool call: %v", err))
+		return
+	}
+
+	arguments := call.Arguments
+	if len(arguments) == 0 {
+		arguments = call.Parameters
+	}
+	// arguments 可能被模型写成 JSON 字符串
+	var argString string
+	if err := json.Unmarshal(arguments, &argString); err != nil {
+		argString = string(arguments)
+	}
+	if strings.TrimSpace(argString) == "" {
+		argString = "{}"
+	}
+
+	p.calls = append(p.calls, model.ToolCall{
+		Index: len(p.calls),
+		ID:    "call_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:24],
+		Type:  "function",
+		Function: model.ToolFunction{
+			Name:      call.Name,
+			Arguments: argString,
+		},
+	})
+}
+
+// partialSuffixLen 返回 s 末尾与 tag 前缀重合的最大长度
+func partialSuffixLen(s, tag string) int {
+	max := len(tag) - 1
+	if max > len(s) {
+		max = len(s)
+	}
+	for n := max; n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -12,6 +12,10 @@ export const LoginPage: React.FC = () => {
   const [password, setPassword] = useState('')
   const [error, setError] = useState('')
   const [isLoading, setIsLoading] = useState(false)
+  // 使用初始密码登录后需先修改密码
+  const [pendingToken, setPendingToken] = useState('')
+  const [newPassword, setNewPassword] = useState('')
+  const [confirmPassword, setConfirmPassword] = useState('')
   
   const navigate = useNavigate()
   const login = useAuthStore((state) => state.login)
@@ -27,13 +31,12 @@ export const LoginPage: React.FC = () => {
         password,
       })
 
-      const { token, user } = response.data
-      login(token, user)
-      
-      // Set the token in axios defaults
-      api.defaults.headers.common['Authorization'] = `Bearer ${token}`
-      
-      navigate('/sessions')
+      const { token, user, refreshToken } = response.data
+      if (user.mustChangePassword) {
+        setPendingToken(token)
+        return
+      }
+      completeLogin(token, user, refreshToken)
     } catch (err: any) {
       setError(err.response?.data?.error || '登录失败，请检查用户名和密码')
     } finally {
@@ -41,6 +44,94 @@ export const LoginPage: React.FC = () => {
     }
   }
 
+  const completeLogin = (token: string, user: { username: string }, refreshToken?: string) => {
+    login(token, user, refreshToken)
+
+    // Set the token in axios defaults
+    api.defaults.headers.common['Authorization'] = `Bearer ${token}`
+
+    navigate('/sessions')
+  }
+
+  const handleChangePassword = async (e: React.FormEvent) => {
+    e.preventDefault()
+    setError('')
+    if (newPassword !== confirmPassword) {
+      setError('两次输入的新密码不一致')
+      return
+    }
+    setIsLoading(true)
+
+    try {
+      const response = await api.post(
+        '/admin/me/password',
+        { currentPassword: password, newPassword },
+        { headers: { Authorization: `Bearer ${pendingToken}` } }
+      )
+      const { token, user, refreshToken } = response.data
+      completeLogin(token, user, refreshToken)
+    } catch (err: any) {
+      const data = err.response?.data
+      setError(data?.fields?.[0]?.message || data?.error || '修改密码失败')
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  if (pendingToken) {
+    return (
+      <div className="min-h-screen flex items-center justify-center bg-gray-50 dark:bg-gray-900">
+        <Card className="w-full max-w-md">
+          <CardHeader className="text-center">
+            <CardTitle className="text-2xl font-bold">修改初始密码</CardTitle>
+            <CardDescription>
+              当前账号使用的是初始密码，修改后才能使用管理功能
+            </CardDescription>
+          </CardHeader>
+          <CardContent>
+            <form onSubmit={handleChangePassword} className="space-y-4">
+              <div className="space-y-2">
+                <Label htmlFor="newPassword">新密码</Label>
+                <Input
+                  id="newPassword"
+                  type="password"
+                  value={newPassword}
+                  onChange={(e) => setNewPassword(e.target.value)}
+                  placeholder="至少 8 个字符"
+                  minLength={8}
+                  required
+                />
+              </div>
+              <div className="space-y-2">
+                <Label htmlFor="confirmPassword">确认新密码</Label>
+                <Input
+                  id="confirmPassword"
+                  type="password"
+                  value={confirmPassword}
+                  onChange={(e) => setConfirmPassword(e.target.value)}
+                  placeholder="再次输入新密码"
+                  required
+                />
+              </div>
+              {error && (
+                <div className="text-red-600 text-sm bg-red-50 dark:bg-red-900/20 p-2 rounded">
+                  {error}
+                </div>
+              )}
+              <Button
+                type="submit"
+                className="w-full"
+                disabled={isLoading}
+              >
+                {isLoading ? '提交中...' : '修改密码并登录'}
+              </Button>
+            </form>
+          </CardContent>
+        </Card>
+      </div>
+    )
+  }
+
   return (
     <div className="min-h-screen flex items-center justify-center bg-gray-50 dark:bg-gray-900">
       <Card className="w-full max-w-md">
@@ -88,7 +179,7 @@ export const LoginPage: React.FC = () => {
             </Button>
           </form>
           <div className="mt-4 text-center text-sm text-gray-500 dark:text-gray-400">
-            默认凭据: admin / admin123
+            首次启动的默认凭据: admin / admin123，登录后需修改密码
           </div>
         </CardContent>
       </Card>
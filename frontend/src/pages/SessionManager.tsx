@@ -20,6 +20,7 @@ import {
 import { SessionCardSkeleton } from '@/components/ui/skeleton';
 
 interface SessionInfo {
+  id: string; // 稳定标识，非 admin 账号看到的 sessionKey 已脱敏，操作时使用 id
   sessionKey: string;
   orgID: string;
   isActive: boolean;
@@ -30,6 +31,10 @@ interface SessionInfo {
   successCount?: number;
   totalRequests?: number;
   avgResponseTime?: number;
+  rateLimitType?: string;
+  resetsAt?: string;
+  tier?: string;
+  tags?: string[];
 }
 
 const SessionManager: React.FC = () => {
@@ -63,6 +68,7 @@ const SessionManager: React.FC = () => {
       const data = await response.json();
       
       const formattedSessions = data.map((session: any) => ({
+        id: session.id || session.session_key || session.sessionKey,
         sessionKey: session.session_key || session.sessionKey,
         orgID: session.org_id || session.orgID || '',
         isActive: session.status === 'active',
@@ -72,7 +78,13 @@ const SessionManager: React.FC = () => {
         errorCount: session.error_count || 0,
         successCount: session.success_count || 0,
         totalRequests: session.total_requests || 0,
-        avgResponseTime: session.avg_response_time || 0
+        avgResponseTime: session.avg_response_time || 0,
+        rateLimitType: session.rate_limit_type || '',
+        resetsAt: session.rate_limit_resets_at && new Date(session.rate_limit_resets_at) > new Date()
+          ? new Date(session.rate_limit_resets_at).toLocaleString()
+          : '',
+        tier: session.tier || '',
+        tags: session.tags || []
       }));
       
       setSessions(formattedSessions);
@@ -113,7 +125,8 @@ const SessionManager: React.FC = () => {
       });
 
       if (!response.ok) {
-        throw new Error(`HTTP error! status: ${response.status}`);
+        const data = await response.json().catch(() => null);
+        throw new Error(data?.verification?.message || data?.error || `HTTP error! status: ${response.status}`);
       }
 
       await response.json();
@@ -132,7 +145,7 @@ const SessionManager: React.FC = () => {
     }
   };
 
-  const handleDeleteSession = async (sessionKey: string) => {
+  const handleDeleteSession = async (sessionID: string) => {
     if (!window.confirm('确定要删除这个Session吗？')) {
       return;
     }
@@ -141,7 +154,7 @@ const SessionManager: React.FC = () => {
     setError(null);
 
     try {
-      const response = await fetch(`/admin/sessions/${encodeURIComponent(sessionKey)}`, {
+      const response = await fetch(`/admin/sessions/${encodeURIComponent(sessionID)}`, {
         method: 'DELETE',
         headers: {
           'Authorization': `Bearer ${localStorage.getItem('apiKey') || 'test-api-key-123'}`,
@@ -164,8 +177,8 @@ const SessionManager: React.FC = () => {
     }
   };
 
-  const handleEditSession = (sessionKey: string, currentOrgID: string) => {
-    setEditingSession(sessionKey);
+  const handleEditSession = (sessionID: string, currentOrgID: string) => {
+    setEditingSession(sessionID);
     setEditOrgID(currentOrgID);
   };
 
@@ -174,12 +187,12 @@ const SessionManager: React.FC = () => {
     setEditOrgID('');
   };
 
-  const handleSaveSession = async (sessionKey: string) => {
+  const handleSaveSession = async (sessionID: string) => {
     setLoading(true);
     setError(null);
 
     try {
-      const response = await fetch(`/admin/sessions/${encodeURIComponent(sessionKey)}`, {
+      const response = await fetch(`/admin/sessions/${encodeURIComponent(sessionID)}`, {
         method: 'PUT',
         headers: {
           'Content-Type': 'application/json',
@@ -225,6 +238,10 @@ const SessionManager: React.FC = () => {
         return <Badge className="bg-red-100 text-red-800 border-red-200">失败</Badge>;
       case 'circuit_open':
         return <Badge className="bg-red-100 text-red-800 border-red-200">熔断</Badge>;
+      case 'disabled':
+        return <Badge className="bg-gray-100 text-gray-800 border-gray-200">已停用</Badge>;
+      case 'draining':
+        return <Badge className="bg-blue-100 text-blue-800 border-blue-200">排空中</Badge>;
       default:
         return <Badge className="bg-gray-100 text-gray-800 border-gray-200">未知</Badge>;
     }
@@ -410,7 +427,7 @@ const SessionManager: React.FC = () => {
               
               {sessions.map((session, index) => (
                 <div
-                  key={session.sessionKey}
+                  key={session.id}
                   className="border border-gray-200 dark:border-gray-700 rounded-lg p-6 hover:shadow-md transition-all duration-200 bg-white dark:bg-gray-800"
                 >
                   <div className="flex items-start justify-between">
@@ -425,6 +442,12 @@ const SessionManager: React.FC = () => {
                             {maskSessionKey(session.sessionKey)}
                           </h3>
                           {getStatusBadge(session.status || 'unknown')}
+                          {session.tier && (
+                            <Badge className="bg-purple-100 text-purple-800 border-purple-200">{session.tier}</Badge>
+                          )}
+                          {session.tags?.map((tag) => (
+                            <Badge key={tag} variant="outline">{tag}</Badge>
+                          ))}
                         </div>
                       </div>
 
@@ -457,7 +480,7 @@ const SessionManager: React.FC = () => {
                       </div>
 
                       {/* Org ID Section */}
-                      {editingSession === session.sessionKey ? (
+                      {editingSession === session.id ? (
                         <div className="flex items-center space-x-2">
                           <Input
                             placeholder="Organization ID"
@@ -468,7 +491,7 @@ const SessionManager: React.FC = () => {
                           />
                           <Button
                             size="sm"
-                            onClick={() => handleSaveSession(session.sessionKey)}
+                            onClick={() => handleSaveSession(session.id)}
                             disabled={loading}
                           >
                             <Save className="w-4 h-4" />
@@ -499,7 +522,7 @@ const SessionManager: React.FC = () => {
                             <Button
                               variant="outline"
                               size="sm"
-                              onClick={() => handleEditSession(session.sessionKey, session.orgID)}
+                              onClick={() => handleEditSession(session.id, session.orgID)}
                               disabled={loading}
                             >
                               <Edit className="w-4 h-4" />
@@ -507,7 +530,7 @@ const SessionManager: React.FC = () => {
                             <Button
                               variant="destructive"
                               size="sm"
-                              onClick={() => handleDeleteSession(session.sessionKey)}
+                              onClick={() => handleDeleteSession(session.id)}
                               disabled={loading}
                             >
                               <Trash2 className="w-4 h-4" />
@@ -523,6 +546,11 @@ const SessionManager: React.FC = () => {
                           <span>成功率: {session.totalRequests ? ((session.successCount || 0) / session.totalRequests * 100).toFixed(1) : 0}%</span>
                         </div>
                         <div className="text-right">
+                          {session.resetsAt && (
+                            <div className="text-orange-600 dark:text-orange-400" title={session.rateLimitType}>
+                              限额重置: {session.resetsAt}
+                            </div>
+                          )}
                           <div>最后使用</div>
                           <div>{session.lastUsed}</div>
                         </div>
@@ -22,6 +22,7 @@ import { SessionHealth, SystemStats } from '@/types';
 import { SessionStatusChart, ResponseTimeChart, SuccessRateChart, HealthScoreChart, CallCountChart, CallRecordsTable, ErrorTypeChart, CircuitBreakerChart, CooldownTimer, WeightInfo, StrategyComparison } from '@/components/ui/charts';
 import { useWebSocket } from '@/hooks/useWebSocket';
 import { apiService } from '@/services/api';
+import { useAuthStore } from '@/stores/auth';
 
 const StatisticsPage: React.FC = () => {
   const [stats, setStats] = useState<SystemStats | null>(null);
@@ -41,8 +42,9 @@ const StatisticsPage: React.FC = () => {
   useWebSocket({
     url: (() => {
       const base = `${window.location.protocol === 'https:' ? 'wss:' : 'ws:'}//${window.location.host}`
-      const apiKey = localStorage.getItem('apiKey') || 'test-api-key-123'
-      const token = encodeURIComponent(apiKey)
+      // /ws 与管理接口使用相同的认证，浏览器无法设置请求头，以查询参数携带登录 token
+      const authToken = useAuthStore.getState().token || localStorage.getItem('apiKey') || ''
+      const token = encodeURIComponent(authToken)
       return `${base}/ws?token=${token}`
     })(),
     onMessage: handleWsMessage,
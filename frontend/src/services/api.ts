@@ -4,6 +4,8 @@ import { useAuthStore } from '../stores/auth';
 
 class ApiService {
   private api: AxiosInstance;
+  // 进行中的刷新请求，刷新 token 只能使用一次，并发的 401 共用同一次刷新
+  private refreshing: Promise<string> | null = null;
 
   constructor() {
     const baseURL = import.meta.env.VITE_API_BASE_URL || '';
@@ -15,19 +17,24 @@ class ApiService {
     // 请求拦截器
     this.api.interceptors.request.use(
       (config) => {
-        // For admin routes, use API key
+        // 管理接口优先使用登录 token；只有服务端配置 adminAuth: jwt_or_apikey 时才接受 API key
         // For other routes, use auth token if available, otherwise API key
         const isAdminRoute = config.url?.startsWith('/admin/') && 
-                            !['/admin/login', '/admin/logout', '/admin/me'].includes(config.url);
+                            !['/admin/login', '/admin/refresh', '/admin/logout', '/admin/logout/all', '/admin/me', '/admin/me/password', '/admin/me/sessions'].includes(config.url);
         
         if (isAdminRoute) {
-          const apiKey = localStorage.getItem('apiKey') || 'test-api-key-123';
-          config.headers.Authorization = `Bearer ${apiKey}`;
+          const auth = useAuthStore.getState();
+          if (auth.token) {
+            config.headers.Authorization = `Bearer ${auth.token}`;
+          } else if (!config.headers.Authorization) {
+            const apiKey = localStorage.getItem('apiKey') || 'test-api-key-123';
+            config.headers.Authorization = `Bearer ${apiKey}`;
+          }
         } else {
           const auth = useAuthStore.getState();
           if (auth.token) {
             config.headers.Authorization = `Bearer ${auth.token}`;
-          } else {
+          } else if (!config.headers.Authorization) {
             const apiKey = localStorage.getItem('apiKey') || 'test-api-key-123';
             config.headers.Authorization = `Bearer ${apiKey}`;
           }
@@ -40,9 +47,27 @@ class ApiService {
     // 响应拦截器
     this.api.interceptors.response.use(
       (response) => response,
-      (error) => {
+      async (error) => {
+        const original = error.config;
+        const auth = useAuthStore.getState();
+        // 访问 token 过期时用刷新 token 换取新 token 并重试一次
+        if (
+          error.response?.status === 401 &&
+          auth.refreshToken &&
+          original &&
+          !original._retried &&
+          !['/admin/login', '/admin/refresh', '/admin/logout'].includes(original.url)
+        ) {
+          original._retried = true;
+          try {
+            const token = await this.refreshAccessToken(auth.refreshToken);
+            original.headers.Authorization = `Bearer ${token}`;
+            return this.api(original);
+          } catch {
+            // 刷新失败时按未登录处理
+          }
+        }
         if (error.response?.status === 401) {
-          const auth = useAuthStore.getState();
           if (auth.isAuthenticated) {
             // If we have an auth token but got 401, clear auth and redirect to login
             auth.logout();
@@ -58,6 +83,22 @@ class ApiService {
     );
   }
 
+  // 用刷新 token 换取新的访问 token
+  private refreshAccessToken(refreshToken: string): Promise<string> {
+    if (!this.refreshing) {
+      this.refreshing = this.api
+        .post('/admin/refresh', { refreshToken })
+        .then((response) => {
+          useAuthStore.getState().setTokens(response.data.token, response.data.refreshToken);
+          return response.data.token as string;
+        })
+        .finally(() => {
+          this.refreshing = null;
+        });
+    }
+    return this.refreshing;
+  }
+
   // 获取模型列表
   async getModels(): Promise<{ data: Model[] }> {
     const response = await this.api.get('/v1/models');
@@ -174,7 +215,8 @@ class ApiService {
 
   // 管理员登出
   async adminLogout() {
-    const response = await this.api.post('/admin/logout');
+    const { refreshToken } = useAuthStore.getState();
+    const response = await this.api.post('/admin/logout', refreshToken ? { refreshToken } : undefined);
     return response.data;
   }
 
@@ -1,11 +1,20 @@
 import { create } from 'zustand'
 import { persist } from 'zustand/middleware'
 
+interface AuthUser {
+  username: string
+  role?: 'viewer' | 'operator' | 'admin'
+  mustChangePassword?: boolean
+}
+
 interface AuthState {
   isAuthenticated: boolean
   token: string | null
-  user: { username: string } | null
-  login: (token: string, user: { username: string }) => void
+  // 访问 token 过期后用于换取新 token，每次刷新都会轮换
+  refreshToken: string | null
+  user: AuthUser | null
+  login: (token: string, user: AuthUser, refreshToken?: string) => void
+  setTokens: (token: string, refreshToken: string) => void
   logout: () => void
 }
 
@@ -14,9 +23,12 @@ export const useAuthStore = create<AuthState>()(
     (set) => ({
       isAuthenticated: false,
       token: null,
+      refreshToken: null,
       user: null,
-      login: (token, user) => set({ isAuthenticated: true, token, user }),
-      logout: () => set({ isAuthenticated: false, token: null, user: null }),
+      login: (token, user, refreshToken) =>
+        set({ isAuthenticated: true, token, user, refreshToken: refreshToken ?? null }),
+      setTokens: (token, refreshToken) => set({ token, refreshToken }),
+      logout: () => set({ isAuthenticated: false, token: null, refreshToken: null, user: null }),
     }),
     {
       name: 'auth-storage',
@@ -14,10 +14,11 @@ export interface Model {
 }
 
 export interface SessionHealth {
-  session_key: string;
+  id: string;
+  session_key: string; // 非 admin 账号获取时已脱敏
   org_id: string;
   health_score: number;
-  status: number; // 0: active, 1: cooling, 2: failed, 3: circuit_open
+  status: number; // 0: active, 1: cooling, 2: failed, 3: circuit_open, 4: disabled, 5: draining
   last_used: string;
   last_error: string;
   cooldown_until: string;
@@ -27,6 +28,14 @@ export interface SessionHealth {
   avg_response_time: number;
   error_types: Record<string, number>;
   weights: number;
+  rate_limit_type?: string;
+  rate_limit_resets_at?: string;
+  rate_limit_tier?: string;
+  tags?: string[];
+  tier?: string;
+  allowed_models?: string[];
+  in_flight?: number;
+  max_concurrent?: number;
   circuit_breaker: {
     state: number;
     failure_count: number;
@@ -68,6 +77,8 @@ export function getStatusText(status: number): string {
     case 1: return 'cooling';
     case 2: return 'failed';
     case 3: return 'circuit_open';
+    case 4: return 'disabled';
+    case 5: return 'draining';
     default: return 'unknown';
   }
 }
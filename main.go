@@ -2,21 +2,93 @@ package main
 
 import (
 	"claude2api/config"
+	"claude2api/logger"
 	"claude2api/router"
 	"claude2api/service"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	if err := config.Init(); err != nil {
+		logger.Fatal(fmt.Sprintf("Configuration validation failed: %v", err))
+	}
+
 	r := gin.Default()
+	// 只信任配置的反向代理提供的 X-Forwarded-For，未配置时使用连接的对端地址作为客户端 IP
+	if err := r.SetTrustedProxies(config.ConfigInstance.TrustedProxies); err != nil {
+		logger.Fatal(fmt.Sprintf("Invalid trustedProxies: %v", err))
+	}
 
-	// Initialize all services (config is already loaded in init())
+	// Initialize all services
 	service.InitServices(config.ConfigInstance)
 
 	// Setup all routes
 	router.SetupRoutes(r)
 
 	// Run the server on 0.0.0.0:8080
-	r.Run(config.ConfigInstance.Address)
+	srv := &http.Server{
+		Addr:    config.ConfigInstance.Address,
+		Handler: r,
+	}
+	tlsSettings := config.ConfigInstance.TLS
+	if tlsSettings.Enabled() {
+		tlsConfig, err := tlsSettings.ServerTLSConfig()
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Invalid TLS config: %v", err))
+		}
+		srv.TLSConfig = tlsConfig
+	}
+	go func() {
+		var err error
+		if tlsSettings.Enabled() {
+			err = srv.ListenAndServeTLS(tlsSettings.CertFile, tlsSettings.KeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal(fmt.Sprintf("Server error: %v", err))
+		}
+	}()
+
+	// Wait for shutdown signal, then save session state before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Hot reload config.yaml on file change or SIGHUP
+	go config.ConfigInstance.WatchConfigFile(ctx)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			logger.Info("Received SIGHUP, reloading config")
+			if _, err := config.ConfigInstance.Reload(); err != nil {
+				logger.Error(fmt.Sprintf("Failed to reload config: %v", err))
+			}
+		}
+	}()
+	<-ctx.Done()
+	logger.Info("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error(fmt.Sprintf("Server shutdown error: %v", err))
+	}
+	if sessionManager := config.ConfigInstance.GetSessionManager(); sessionManager != nil {
+		if err := sessionManager.Close(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to save session state: %v", err))
+		}
+	}
+	if err := config.ConfigInstance.GetAPIKeyRegistry().Close(); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save api key usage: %v", err))
+	}
 }
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"claude2api/config"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 管理端token类型，刷新token不是 JWT
+const adminAccessTokenType = "access"
+
+// ErrInvalidAdminToken token 签名错误、已过期或缺少必要的声明
+var ErrInvalidAdminToken = errors.New("invalid or expired token")
+
+// AdminTokenPair 登录或刷新时签发的访问token与刷新token
+type AdminTokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// adminTokenClaims 访问token中用到的声明
+type adminTokenClaims struct {
+	Username          string
+	JTI               string
+	Family            string
+	ExpiresAt         time.Time
+	PasswordChangedAt int64 // 签发时的密码修改时间（毫秒）
+}
+
+// IssueAdminTokens 登录时创建新的token族，签发访问token与刷新token
+func IssueAdminTokens(user *config.AdminUser) (*AdminTokenPair, error) {
+	family, refresh, err := config.ConfigInstance.GetAdminTokenStore().CreateFamily(user.Username, config.ConfigInstance.GetAdminRefreshTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+	return signAdminTokenPair(user, family, refresh)
+}
+
+// RefreshAdminTokens 轮换刷新token并签发新的访问token，账号不存在或已修改密码时拒绝
+func RefreshAdminTokens(refresh string) (*AdminTokenPair, *config.AdminUser, error) {
+	tokenStore := config.ConfigInstance.GetAdminTokenStore()
+	family, next, err := tokenStore.Rotate(refresh, config.ConfigInstance.GetAdminRefreshTokenTTL())
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := config.ConfigInstance.GetAdminUserStore().Get(family.Username)
+	if err != nil {
+		tokenStore.RevokeFamily(family.ID, "", time.Time{})
+		return nil, nil, config.ErrRefreshTokenInvalid
+	}
+	pair, err := signAdminTokenPair(user, family, next)
+	return pair, user, err
+}
+
+// signAdminTokenPair 为token族签发访问token
+func signAdminTokenPair(user *config.AdminUser, family *config.AdminTokenFamily, refresh string) (*AdminTokenPair, error) {
+	jti, err := randomTokenID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(config.ConfigInstance.GetAdminAccessTokenTTL())
+	claims := jwt.MapClaims{
+		"username": user.Username,
+		"pwd":      user.PasswordChangedAt.UnixMilli(),
+		"typ":      adminAccessTokenType,
+		"jti":      jti,
+		"fam":      family.ID,
+		"exp":      expiresAt.Unix(),
+		"iat":      now.Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.ConfigInstance.GetAdminSecret()))
+	if err != nil {
+		return nil, err
+	}
+	return &AdminTokenPair{
+		AccessToken:      token,
+		AccessExpiresAt:  expiresAt,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: family.ExpiresAt,
+	}, nil
+}
+
+// parseAdminToken 校验访问token的签名与声明，verifyExpiry 为 false 时接受已过期的token（用于退出登录）
+func parseAdminToken(tokenString string, verifyExpiry bool) (*adminTokenClaims, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()})}
+	if !verifyExpiry {
+		options = append(options, jwt.WithoutClaimsValidation())
+	}
+	token, err := jwt.NewParser(options...).Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.ConfigInstance.GetAdminSecret()), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidAdminToken
+	}
+
+	mapClaims, _ := token.Claims.(jwt.MapClaims)
+	claims := &adminTokenClaims{}
+	claims.Username, _ = mapClaims["username"].(string)
+	claims.JTI, _ = mapClaims["jti"].(string)
+	claims.Family, _ = mapClaims["fam"].(string)
+	tokenType, _ := mapClaims["typ"].(string)
+	expiresAt, _ := mapClaims["exp"].(float64)
+	pwd, _ := mapClaims["pwd"].(float64)
+	claims.ExpiresAt = time.Unix(int64(expiresAt), 0)
+	claims.PasswordChangedAt = int64(pwd)
+	// 旧版本签发的token没有 jti 与token族，无法吊销，需要重新登录
+	if tokenType != adminAccessTokenType || claims.Username == "" || claims.JTI == "" || claims.Family == "" {
+		return nil, ErrInvalidAdminToken
+	}
+	return claims, nil
+}
+
+// RevokeAdminToken 吊销访问token及其所属的token族，已过期的token也可以用于退出登录
+func RevokeAdminToken(tokenString string) error {
+	claims, err := parseAdminToken(tokenString, false)
+	if err != nil {
+		return err
+	}
+	return config.ConfigInstance.GetAdminTokenStore().RevokeFamily(claims.Family, claims.JTI, claims.ExpiresAt)
+}
+
+// randomTokenID 生成访问token的 jti
+func randomTokenID() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
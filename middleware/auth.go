@@ -2,12 +2,14 @@ package middleware
 
 import (
 	"claude2api/config"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 )
 
 // AdminAuthMiddleware 管理员认证中间件
@@ -19,8 +21,18 @@ func AdminAuthMiddleware() gin.HandlerFunc {
             return
         }
 
+		// mtls：先要求经过验证的客户端证书
+		if !adminClientCertAllowed(c) {
+			abortClientCertRequired(c)
+			return
+		}
+
 		// 检查JWT token
 		tokenString := c.GetHeader("Authorization")
+		// 浏览器的 WebSocket 无法设置请求头，/ws 通过查询参数携带token
+		if tokenString == "" && strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			tokenString = c.Query("token")
+		}
 		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization token"})
 			c.Abort()
@@ -28,21 +40,30 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+
+		// 管理API token：只能访问其权限范围内的接口
+		if strings.HasPrefix(tokenString, config.AdminAPITokenPrefix) {
+			token, err := config.ConfigInstance.GetAdminAPITokenStore().Authenticate(tokenString)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+			c.Set("admin_user", "token:"+token.Name)
+			c.Set("admin_api_token", token)
+			c.Next()
+			return
+		}
 		
 		// 解析JWT token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(config.ConfigInstance.GetAdminSecret()), nil
-		})
-
-        if err != nil || !token.Valid {
-            // 兼容旧版：若提供了 API Key，也允许访问（不建议，后续可移除）
-            apiKey := c.GetHeader("Authorization")
-            apiKey = strings.TrimPrefix(apiKey, "Bearer ")
-            if apiKey != "" && apiKey == config.ConfigInstance.APIKey {
+		claims, err := parseAdminToken(tokenString, true)
+
+        if err != nil {
+            // jwt_or_apikey：兼容旧版，JWT 无效时也接受业务 API Key（不建议）
+            apiKey := tokenString
+            if config.ConfigInstance.GetAdminAuth() == config.AdminAuthJWTOrAPIKey && apiKey != "" && apiKey == config.ConfigInstance.APIKey {
                 c.Set("admin_user", "apikey")
+                c.Set("admin_role", config.AdminRoleAdmin)
                 c.Next()
                 return
             }
@@ -51,15 +72,109 @@ func AdminAuthMiddleware() gin.HandlerFunc {
             return
         }
 
+		// 检查吊销列表：退出登录后token族内的token全部失效
+		if err := config.ConfigInstance.GetAdminTokenStore().CheckAccess(claims.JTI, claims.Family); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// 每次请求从账号存储读取角色，删除账号或修改密码后此前的token立即失效
+		user, err := config.ConfigInstance.GetAdminUserStore().Get(claims.Username)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+		if claims.PasswordChangedAt != user.PasswordChangedAt.UnixMilli() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Password has changed, please log in again"})
+			c.Abort()
+			return
+		}
+
 		// 设置用户信息到上下文
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("admin_user", claims["username"])
+		c.Set("admin_user", user.Username)
+		c.Set("admin_role", user.Role)
+		c.Set("admin_token_family", claims.Family)
+
+		// 使用初始密码的账号只能查看自己的信息与修改密码
+		if user.MustChangePassword && !passwordChangeAllowed(c) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":                  "Password change required before using the admin API",
+				"passwordChangeRequired": true,
+			})
+			c.Abort()
+			return
 		}
 
 		c.Next()
 	}
 }
 
+// passwordChangeAllowed 需要修改初始密码时仍可访问的管理接口
+func passwordChangeAllowed(c *gin.Context) bool {
+	switch c.Request.Method + " " + c.Request.URL.Path {
+	case "GET /admin/me", "POST /admin/me/password", "POST /admin/logout/all":
+		return true
+	default:
+		return false
+	}
+}
+
+// RequireAdminAccess 要求管理员账号至少具有 role 角色，管理API token需要包含 scope 权限范围，
+// scope 为空表示管理API token不能访问，需放在 AdminAuthMiddleware 之后
+func RequireAdminAccess(role, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if HasAdminAccess(c, role, scope) {
+			c.Next()
+			return
+		}
+		message := fmt.Sprintf("This action requires the %s role", role)
+		if _, isToken := c.Get("admin_api_token"); isToken {
+			message = "This action is not available to admin API tokens"
+			if scope != "" {
+				message = fmt.Sprintf("This action requires the %s scope", scope)
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		c.Abort()
+	}
+}
+
+// HasAdminAccess 当前管理员账号是否具有 role 角色，或管理API token是否包含 scope
+func HasAdminAccess(c *gin.Context, role, scope string) bool {
+	if value, isToken := c.Get("admin_api_token"); isToken {
+		token, _ := value.(*config.AdminAPIToken)
+		return scope != "" && token != nil && token.HasScope(scope)
+	}
+	return config.AdminRoleAllows(c.GetString("admin_role"), role)
+}
+
+// AdminClientCertMiddleware mtls 模式下要求客户端证书，用于 AdminAuthMiddleware 之外的登录、刷新与退出接口
+func AdminClientCertMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !adminClientCertAllowed(c) {
+			abortClientCertRequired(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// adminClientCertAllowed 非 mtls 模式总是允许；mtls 模式要求通过 tls.clientCAFile 验证的客户端证书
+func adminClientCertAllowed(c *gin.Context) bool {
+	if config.ConfigInstance.GetAdminAuth() != config.AdminAuthMTLS {
+		return true
+	}
+	return c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0
+}
+
+// abortClientCertRequired 返回缺少客户端证书的错误
+func abortClientCertRequired(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"error": "A verified client certificate is required for the admin API"})
+	c.Abort()
+}
+
 // AuthMiddleware initializes the Claude client from the request header
 func AuthMiddleware() gin.HandlerFunc {
     return func(c *gin.Context) {
@@ -69,52 +184,76 @@ func AuthMiddleware() gin.HandlerFunc {
             return
         }
         Key := c.GetHeader("Authorization")
-        if Key != "" {
-            Key = strings.TrimPrefix(Key, "Bearer ")
-            if Key != config.ConfigInstance.APIKey {
-                c.JSON(401, gin.H{
-                    "error": "Invalid API key",
-                })
-                c.Abort()
-                return
-            }
-            c.Next()
+        if Key == "" {
+            // Anthropic SDK 使用 x-api-key 头
+            Key = c.GetHeader("x-api-key")
+        }
+        if Key == "" {
+            c.JSON(401, gin.H{
+                "error": "Missing or invalid Authorization header",
+            })
+            c.Abort()
             return
         }
-        // 兼容 WebSocket 或无法自定义头的场景：支持查询参数 token / api_key
-        if c.Request.URL.Path == "/ws" {
-            qp := c.Query("token")
-            if qp == "" {
-                qp = c.Query("api_key")
-            }
-            if qp != "" && qp == config.ConfigInstance.APIKey {
-                c.Next()
-                return
-            }
+        Key = strings.TrimPrefix(Key, "Bearer ")
+        identity, err := authenticateAPIKey(Key)
+        if err != nil {
+            abortAPIKeyError(c, Key, err)
+            return
         }
-        c.JSON(401, gin.H{
-            "error": "Missing or invalid Authorization header",
-        })
-        c.Abort()
+        // Key 身份供处理函数与统计使用
+        c.Set("APIKeyID", identity.ID)
+        c.Set("APIKeyIdentity", identity)
+        c.Next()
+        recordAPIKeyUsage(c, identity)
     }
 }
 
-// GenerateAdminToken 生成管理员JWT token
-func GenerateAdminToken(username string) (string, error) {
-	claims := jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(24 * time.Hour).Unix(),
-		"iat":      time.Now().Unix(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.ConfigInstance.GetAdminSecret()))
-}
-
-// ValidateAdminCredentials 验证管理员凭据
-func ValidateAdminCredentials(username, password string) bool {
-	adminUser := config.ConfigInstance.GetAdminUser()
-	adminPass := config.ConfigInstance.GetAdminPassword()
-	
-	return username == adminUser && password == adminPass
+// authenticateAPIKey 校验配置中的 apiKey 或注册表中的客户端Key
+func authenticateAPIKey(key string) (*config.APIKeyIdentity, error) {
+	if apiKey := config.ConfigInstance.APIKey; apiKey != "" && key == apiKey {
+		return config.DefaultAPIKeyIdentity(), nil
+	}
+	return config.ConfigInstance.GetAPIKeyRegistry().Authenticate(key)
+}
+
+// abortAPIKeyError 按Key校验失败的原因返回 401，超过每分钟请求数或当日token预算时返回 429
+func abortAPIKeyError(c *gin.Context, key string, err error) {
+	switch {
+	case errors.Is(err, config.ErrAPIKeyRateLimited), errors.Is(err, config.ErrAPIKeyBudgetExhausted):
+		retryAfter := int(math.Ceil(config.ConfigInstance.GetAPIKeyRegistry().RetryAfter(key).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, config.ErrAPIKeyRevoked), errors.Is(err, config.ErrAPIKeyExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+	}
+	c.Abort()
+}
+
+// recordAPIKeyUsage 为客户端Key计入请求数，请求成功时再计入估算的输入与输出token
+// 处理函数拒绝该Key（如模型不在允许范围内）时不计入，并退回占用的每分钟请求数
+func recordAPIKeyUsage(c *gin.Context, identity *config.APIKeyIdentity) {
+	if identity.ID == config.DefaultAPIKeyID {
+		return
+	}
+	registry := config.ConfigInstance.GetAPIKeyRegistry()
+	if c.GetBool("APIKeyDenied") {
+		registry.ReleaseRequest(identity.ID)
+		return
+	}
+	registry.RecordRequest(identity.ID)
+	if c.Writer.Status() >= http.StatusBadRequest {
+		return
+	}
+	registry.RecordTokens(identity.ID, c.GetInt("PromptTokens")+c.GetInt("CompletionTokens"))
+}
+
+// ValidateAdminCredentials 验证管理员凭据，返回对应的账号
+func ValidateAdminCredentials(username, password string) (*config.AdminUser, error) {
+	return config.ConfigInstance.GetAdminUserStore().Authenticate(username, password)
 }
@@ -29,7 +29,7 @@ func CORSMiddleware() gin.HandlerFunc {
             c.Writer.Header().Set("Vary", "Origin")
         }
         c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-        c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, Authorization")
+        c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, Authorization, x-api-key, anthropic-version")
         if c.Request.Method == "OPTIONS" {
             c.AbortWithStatus(204)
             return
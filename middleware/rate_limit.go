@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"claude2api/config"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenBucket 令牌桶：容量为每个窗口的配额，按 容量/窗口 的速率匀速补充
+type tokenBucket struct {
+	capacity  float64
+	available float64
+	updated   time.Time
+}
+
+// refill 按经过的时间补充令牌，配额变化时按新容量截断
+func (b *tokenBucket) refill(capacity float64, window time.Duration, now time.Time) {
+	if b.updated.IsZero() {
+		b.available = capacity
+	} else {
+		b.available += capacity * now.Sub(b.updated).Seconds() / window.Seconds()
+	}
+	b.capacity = capacity
+	b.available = math.Min(b.available, capacity)
+	b.updated = now
+}
+
+// waitFor 返回令牌数达到 n 所需的时间
+func (b *tokenBucket) waitFor(n float64, window time.Duration) time.Duration {
+	if b.available >= n || b.capacity <= 0 {
+		return 0
+	}
+	return time.Duration((n - b.available) / b.capacity * float64(window))
+}
+
+// remaining 返回剩余的整数令牌数
+func (b *tokenBucket) remaining() int {
+	return int(math.Max(0, math.Floor(b.available)))
+}
+
+// rateLimitClient 一个限流对象的请求数与 token 数令牌桶
+type rateLimitClient struct {
+	requests tokenBucket
+	tokens   tokenBucket
+	lastSeen time.Time
+}
+
+// rateLimitResult 一次限流检查的结果，用于写入响应头
+type rateLimitResult struct {
+	allowed    bool
+	requests   tokenBucket
+	tokens     tokenBucket
+	retryAfter time.Duration
+}
+
+// maxRateLimitClients 同时保存的限流对象上限，超过时先清理空闲对象，仍然超过时随机淘汰
+const maxRateLimitClients = 100000
+
+// rateLimiter 按限流对象保存令牌桶
+type rateLimiter struct {
+	clients   map[string]*rateLimitClient
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+var inboundLimiter = &rateLimiter{clients: make(map[string]*rateLimitClient)}
+
+// take 检查并消耗一个请求令牌；估算 token 的令牌在请求结束后由 consumeTokens 扣除，
+// 因此只要求剩余 token 为正，超用的部分会推迟下一次请求
+func (l *rateLimiter) take(key string, cfg config.RateLimitConfig) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(cfg, now, false)
+	client, exists := l.clients[key]
+	if !exists {
+		if len(l.clients) >= maxRateLimitClients {
+			l.evict(cfg, now)
+		}
+		client = &rateLimitClient{}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	result := rateLimitResult{allowed: true}
+	if cfg.Requests > 0 {
+		client.requests.refill(float64(cfg.Requests), cfg.Window, now)
+		if wait := client.requests.waitFor(1, cfg.Window); wait > 0 {
+			result.allowed = false
+			result.retryAfter = wait
+		}
+	}
+	if cfg.Tokens > 0 {
+		client.tokens.refill(float64(cfg.Tokens), cfg.Window, now)
+		if client.tokens.available <= 0 {
+			result.allowed = false
+			if wait := client.tokens.waitFor(1, cfg.Window); wait > result.retryAfter {
+				result.retryAfter = wait
+			}
+		}
+	}
+	if result.allowed && cfg.Requests > 0 {
+		client.requests.available--
+	}
+	result.requests = client.requests
+	result.tokens = client.tokens
+	return result
+}
+
+// consumeTokens 扣除请求实际使用的估算 token 数
+func (l *rateLimiter) consumeTokens(key string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if client, exists := l.clients[key]; exists {
+		client.tokens.available -= float64(tokens)
+	}
+}
+
+// sweep 每个窗口清理一次超过一个窗口未出现、令牌桶已经补满的限流对象，force 时不检查间隔，调用方需持有锁
+func (l *rateLimiter) sweep(cfg config.RateLimitConfig, now time.Time, force bool) {
+	if !force && now.Sub(l.lastSweep) < cfg.Window {
+		return
+	}
+	l.lastSweep = now
+	for key, client := range l.clients {
+		idle := now.Sub(client.lastSeen)
+		if idle <= cfg.Window {
+			continue
+		}
+		// token 超用的对象在补满之前保留，避免借删除绕过限流
+		if client.tokens.available < 0 && cfg.Tokens > 0 &&
+			client.tokens.available+float64(cfg.Tokens)*idle.Seconds()/cfg.Window.Seconds() < float64(cfg.Tokens) {
+			continue
+		}
+		delete(l.clients, key)
+	}
+}
+
+// evict 限流对象达到上限时清理空闲对象，仍然超过上限时随机淘汰十分之一，调用方需持有锁
+func (l *rateLimiter) evict(cfg config.RateLimitConfig, now time.Time) {
+	l.sweep(cfg, now, true)
+	if len(l.clients) < maxRateLimitClients {
+		return
+	}
+	excess := len(l.clients) - maxRateLimitClients*9/10
+	for key := range l.clients {
+		if excess <= 0 {
+			break
+		}
+		delete(l.clients, key)
+		excess--
+	}
+}
+
+// rateLimitKey 按配置的维度返回限流对象
+func rateLimitKey(c *gin.Context, keyBy string) string {
+	apiKeyID := c.GetString("APIKeyID")
+	switch keyBy {
+	case config.RateLimitKeyIP:
+		return "ip:" + c.ClientIP()
+	case config.RateLimitKeyAPIKeyIP:
+		return "key:" + apiKeyID + "|ip:" + c.ClientIP()
+	default:
+		if apiKeyID == "" {
+			return "ip:" + c.ClientIP()
+		}
+		return "key:" + apiKeyID
+	}
+}
+
+// formatRateLimitReset 以 OpenAI 的格式输出重置时间，如 20ms、1s、6m0s
+func formatRateLimitReset(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
+
+// RateLimitMiddleware 业务 API 的入站限流，需放在 AuthMiddleware 之后以便按 API Key 计数
+// 每个响应都带有 OpenAI 格式的 x-ratelimit-* 头，超限时返回 429 与 Retry-After
+func RateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.ConfigInstance.GetRateLimit()
+		if !cfg.Enabled || (cfg.Requests <= 0 && cfg.Tokens <= 0) {
+			c.Next()
+			return
+		}
+
+		key := rateLimitKey(c, cfg.KeyBy)
+		result := inboundLimiter.take(key, cfg)
+		if cfg.Requests > 0 {
+			c.Header("x-ratelimit-limit-requests", strconv.Itoa(cfg.Requests))
+			c.Header("x-ratelimit-remaining-requests", strconv.Itoa(result.requests.remaining()))
+			c.Header("x-ratelimit-reset-requests", formatRateLimitReset(result.requests.waitFor(result.requests.capacity, cfg.Window)))
+		}
+		if cfg.Tokens > 0 {
+			c.Header("x-ratelimit-limit-tokens", strconv.Itoa(cfg.Tokens))
+			c.Header("x-ratelimit-remaining-tokens", strconv.Itoa(result.tokens.remaining()))
+			c.Header("x-ratelimit-reset-tokens", formatRateLimitReset(result.tokens.waitFor(result.tokens.capacity, cfg.Window)))
+		}
+
+		if !result.allowed {
+			retryAfter := int(math.Ceil(result.retryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.Header("retry-after-ms", strconv.FormatInt(result.retryAfter.Milliseconds(), 10))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": fmt.Sprintf("Rate limit exceeded, retry after %s", formatRateLimitReset(result.retryAfter)),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		if cfg.Tokens > 0 && c.Writer.Status() < http.StatusBadRequest {
+			inboundLimiter.consumeTokens(key, c.GetInt("PromptTokens")+c.GetInt("CompletionTokens"))
+		}
+	}
+}
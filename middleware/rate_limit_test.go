@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"claude2api/config"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketRefill(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		bucket        tokenBucket
+		capacity      float64
+		elapsed       time.Duration
+		wantAvailable float64
+	}{
+		{name: "first use starts full", capacity: 10, wantAvailable: 10},
+		{name: "half window refills half", bucket: tokenBucket{capacity: 10, available: 0, updated: start}, capacity: 10, elapsed: 30 * time.Second, wantAvailable: 5},
+		{name: "refill is capped at capacity", bucket: tokenBucket{capacity: 10, available: 8, updated: start}, capacity: 10, elapsed: time.Hour, wantAvailable: 10},
+		{name: "no time passed adds nothing", bucket: tokenBucket{capacity: 10, available: 3, updated: start}, capacity: 10, wantAvailable: 3},
+		{name: "overuse recovers from negative", bucket: tokenBucket{capacity: 100, available: -50, updated: start}, capacity: 100, elapsed: 45 * time.Second, wantAvailable: 25},
+		{name: "lower quota truncates", bucket: tokenBucket{capacity: 10, available: 10, updated: start}, capacity: 4, elapsed: time.Second, wantAvailable: 4},
+		{name: "higher quota refills at new rate", bucket: tokenBucket{capacity: 10, available: 0, updated: start}, capacity: 20, elapsed: 15 * time.Second, wantAvailable: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := tt.bucket
+			bucket.refill(tt.capacity, time.Minute, start.Add(tt.elapsed))
+			if math.Abs(bucket.available-tt.wantAvailable) > 1e-9 {
+				t.Fatalf("available = %v, want %v", bucket.available, tt.wantAvailable)
+			}
+			if bucket.capacity != tt.capacity {
+				t.Fatalf("capacity = %v, want %v", bucket.capacity, tt.capacity)
+			}
+		})
+	}
+}
+
+func TestTokenBucketWaitFor(t *testing.T) {
+	tests := []struct {
+		name          string
+		bucket        tokenBucket
+		n             float64
+		wantWait      time.Duration
+		wantRemaining int
+	}{
+		{name: "enough tokens", bucket: tokenBucket{capacity: 10, available: 3}, n: 1, wantWait: 0, wantRemaining: 3},
+		{name: "empty bucket waits one interval", bucket: tokenBucket{capacity: 10, available: 0}, n: 1, wantWait: 6 * time.Second, wantRemaining: 0},
+		{name: "partial token", bucket: tokenBucket{capacity: 10, available: 0.5}, n: 1, wantWait: 3 * time.Second, wantRemaining: 0},
+		{name: "overdrawn bucket waits for the debt", bucket: tokenBucket{capacity: 60, available: -59}, n: 1, wantWait: time.Minute, wantRemaining: 0},
+		{name: "zero capacity never waits", bucket: tokenBucket{}, n: 1, wantWait: 0, wantRemaining: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if wait := tt.bucket.waitFor(tt.n, time.Minute); wait != tt.wantWait {
+				t.Fatalf("waitFor = %v, want %v", wait, tt.wantWait)
+			}
+			if remaining := tt.bucket.remaining(); remaining != tt.wantRemaining {
+				t.Fatalf("remaining = %d, want %d", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.RateLimitConfig
+		usedTokens  int // 每个放行的请求结束后扣除的 token 数
+		wantAllowed int
+	}{
+		{name: "request quota allows a full burst", cfg: config.RateLimitConfig{Requests: 5, Window: time.Minute}, wantAllowed: 5},
+		{name: "token quota stops once spent", cfg: config.RateLimitConfig{Tokens: 1000, Window: time.Minute}, usedTokens: 400, wantAllowed: 3},
+		{name: "stricter quota wins", cfg: config.RateLimitConfig{Requests: 5, Tokens: 1000, Window: time.Minute}, usedTokens: 600, wantAllowed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &rateLimiter{clients: make(map[string]*rateLimitClient)}
+			allowed := 0
+			var last rateLimitResult
+			for i := 0; i < 10; i++ {
+				last = limiter.take("client", tt.cfg)
+				if !last.allowed {
+					break
+				}
+				allowed++
+				limiter.consumeTokens("client", tt.usedTokens)
+			}
+			if allowed != tt.wantAllowed {
+				t.Fatalf("allowed %d requests, want %d", allowed, tt.wantAllowed)
+			}
+			if last.allowed {
+				t.Fatal("burst was never limited")
+			}
+			if last.retryAfter <= 0 || last.retryAfter > tt.cfg.Window {
+				t.Fatalf("retryAfter = %v, want within (0, %v]", last.retryAfter, tt.cfg.Window)
+			}
+		})
+	}
+}
@@ -0,0 +1,481 @@
+package model
+
+import (
+	"claude2api/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ErrGenerationStopped 表示输出已因 stop_sequences 等原因提前结束，无需继续读取上游
+var ErrGenerationStopped = errors.New("generation stopped")
+
+// AnthropicMessagesRequest Anthropic Messages API 请求
+type AnthropicMessagesRequest struct {
+	Model         string                   `json:"model"`
+	System        interface{}              `json:"system,omitempty"`
+	Messages      []map[string]interface{} `json:"messages"`
+	MaxTokens     int                      `json:"max_tokens"`
+	StopSequences []string                 `json:"stop_sequences,omitempty"`
+	Stream        bool                     `json:"stream"`
+	Tools         []map[string]interface{} `json:"tools,omitempty"`
+	ToolChoice    map[string]interface{}   `json:"tool_choice,omitempty"`
+	// ThinkingMode 覆盖全局 thinkingMode；reasoning_content 模式下输出 thinking 内容块
+	ThinkingMode string `json:"thinking_mode,omitempty"`
+}
+
+// AnthropicContentBlock 响应中的内容块
+type AnthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// AnthropicToolUseBlock tool_use 内容块
+type AnthropicToolUseBlock struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// AnthropicResponse 非流式响应
+type AnthropicResponse struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Role         string         `json:"role"`
+	Model        string         `json:"model"`
+	Content      []interface{}  `json:"content"`
+	StopReason   *string        `json:"stop_reason"`
+	StopSequence *string        `json:"stop_sequence"`
+	Usage        AnthropicUsage `json:"usage"`
+}
+
+// ToOpenAIMessages 将 Anthropic 格式的 system 与 messages 转换为 ChatRequestProcessor 可处理的 OpenAI 格式
+func (r *AnthropicMessagesRequest) ToOpenAIMessages() []map[string]interface{} {
+	messages := make([]map[string]interface{}, 0, len(r.Messages)+1)
+
+	if system := anthropicContentToOpenAI(r.System); system != nil {
+		messages = append(messages, map[string]interface{}{
+			"role":    "system",
+			"content": system,
+		})
+	}
+
+	for _, msg := range r.Messages {
+		// tool_result 块转换为 role 为 tool 的消息，放在本轮文本之前
+		blocks, _ := msg["content"].([]interface{})
+		for _, item := range blocks {
+			block, ok := item.(map[string]interface{})
+			if !ok || block["type"] != "tool_result" {
+				continue
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":         "tool",
+				"tool_call_id": block["tool_use_id"],
+				"content":      anthropicContentToOpenAI(block["content"]),
+			})
+		}
+
+		converted := map[string]interface{}{"role": msg["role"]}
+		if toolCalls := anthropicToolUseToOpenAI(blocks); len(toolCalls) > 0 {
+			converted["tool_calls"] = toolCalls
+		}
+		content := anthropicContentToOpenAI(msg["content"])
+		if content == nil && converted["tool_calls"] == nil {
+			continue
+		}
+		converted["content"] = content
+		messages = append(messages, converted)
+	}
+	return messages
+}
+
+// ToOpenAITools 将 Anthropic 的 tools 与 tool_choice 转换为 OpenAI 格式
+func (r *AnthropicMessagesRequest) ToOpenAITools() ([]map[string]interface{}, interface{}) {
+	tools := make([]map[string]interface{}, 0, len(r.Tools))
+	for _, tool := range r.Tools {
+		tools = append(tools, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool["name"],
+				"description": tool["description"],
+				"parameters":  tool["input_schema"],
+			},
+		})
+	}
+
+	var toolChoice interface{}
+	switch r.ToolChoice["type"] {
+	case "any":
+		toolChoice = "required"
+	case "none":
+		toolChoice = "none"
+	case "tool":
+		toolChoice = map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": r.ToolChoice["name"]},
+		}
+	}
+	return tools, toolChoice
+}
+
+// anthropicToolUseToOpenAI 提取 assistant 消息中的 tool_use 块
+func anthropicToolUseToOpenAI(blocks []interface{}) []interface{} {
+	var toolCalls []interface{}
+	for _, item := range blocks {
+		block, ok := item.(map[string]interface{})
+		if !ok || block["type"] != "tool_use" {
+			continue
+		}
+		arguments, _ := json.Marshal(block["input"])
+		toolCalls = append(toolCalls, map[string]interface{}{
+			"id":   block["id"],
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":      block["name"],
+				"arguments": string(arguments),
+			},
+		})
+	}
+	return toolCalls
+}
+
+// anthropicContentToOpenAI 转换单个 content 字段（字符串或内容块数组）
+func anthropicContentToOpenAI(content interface{}) interface{} {
+	switch v := content.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return v
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			block, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch block["type"] {
+			case "text":
+				if text, ok := block["text"].(string); ok {
+					items = append(items, map[string]interface{}{"type": "text", "text": text})
+				}
+			case "image":
+				source, ok := block["source"].(map[string]interface{})
+				if !ok || source["type"] != "base64" {
+					continue
+				}
+				mediaType, _ := source["media_type"].(string)
+				data, _ := source["data"].(string)
+				items = append(items, map[string]interface{}{
+					"type": "image_url",
+					"image_url": map[string]interface{}{
+						"url": fmt.Sprintf("data:%s;base64,%s", mediaType, data),
+					},
+				})
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return items
+	default:
+		return nil
+	}
+}
+
+// AnthropicWriter 输出 Anthropic Messages 格式，支持 stop_sequences 与 tool_use
+type AnthropicWriter struct {
+	gc            *gin.Context
+	stream        bool
+	id            string
+	model         string
+	stopSequences []string
+	pending       string
+	text          strings.Builder
+	thinking      strings.Builder
+	toolUses      []AnthropicToolUseBlock
+	started       bool
+	openBlock     string
+	blockIndex    int
+	stopped       bool
+	stopSequence  string
+	usage         Usage
+}
+
+// NewAnthropicWriter creates a writer for the Anthropic Messages format
+func NewAnthropicWriter(gc *gin.Context, stream bool, model string, stopSequences []string) *AnthropicWriter {
+	return &AnthropicWriter{
+		gc:            gc,
+		stream:        stream,
+		id:            "msg_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		model:         model,
+		stopSequences: stopSequences,
+	}
+}
+
+func (w *AnthropicWriter) WriteText(text string) error {
+	if w.stopped {
+		return ErrGenerationStopped
+	}
+	w.pending += text
+
+	// 命中 stop sequence 时截断输出
+	cut, matched := -1, ""
+	for _, seq := range w.stopSequences {
+		if seq == "" {
+			continue
+		}
+		if idx := strings.Index(w.pending, seq); idx >= 0 && (cut < 0 || idx < cut) {
+			cut, matched = idx, seq
+		}
+	}
+	if cut >= 0 {
+		w.emit(w.pending[:cut])
+		w.pending = ""
+		w.stopped = true
+		w.stopSequence = matched
+		return ErrGenerationStopped
+	}
+
+	// 保留可能构成 stop sequence 前缀的尾部
+	hold := 0
+	for _, seq := range w.stopSequences {
+		if len(seq)-1 > hold {
+			hold = len(seq) - 1
+		}
+	}
+	if hold >= len(w.pending) {
+		return nil
+	}
+	split := len(w.pending) - hold
+	for split > 0 && split < len(w.pending) && !utf8.RuneStart(w.pending[split]) {
+		split--
+	}
+	out := w.pending[:split]
+	w.pending = w.pending[split:]
+	return w.emit(out)
+}
+
+func (w *AnthropicWriter) WriteReasoning(text string) error {
+	w.thinking.WriteString(text)
+	if !w.stream || text == "" {
+		return nil
+	}
+	w.startBlock("thinking")
+	return w.writeEvent("content_block_delta", gin.H{
+		"type":  "content_block_delta",
+		"index": w.blockIndex,
+		"delta": gin.H{"type": "thinking_delta", "thinking": text},
+	})
+}
+
+func (w *AnthropicWriter) WriteToolCalls(calls []ToolCall) error {
+	w.flushPending()
+	w.closeBlock()
+	for _, call := range calls {
+		input := json.RawMessage(call.Function.Arguments)
+		if !json.Valid(input) {
+			input = json.RawMessage("{}")
+		}
+		block := AnthropicToolUseBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input}
+		w.toolUses = append(w.toolUses, block)
+		if !w.stream {
+			continue
+		}
+		w.start()
+		w.writeEvent("content_block_start", gin.H{
+			"type":          "content_block_start",
+			"index":         w.blockIndex,
+			"content_block": AnthropicToolUseBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: json.RawMessage("{}")},
+		})
+		w.writeEvent("content_block_delta", gin.H{
+			"type":  "content_block_delta",
+			"index": w.blockIndex,
+			"delta": gin.H{"type": "input_json_delta", "partial_json": string(input)},
+		})
+		w.writeEvent("content_block_stop", gin.H{"type": "content_block_stop", "index": w.blockIndex})
+		w.blockIndex++
+	}
+	return nil
+}
+
+func (w *AnthropicWriter) WriteError(message string) error {
+	if !w.stream {
+		ReturnAnthropicError(w.gc, 502, "api_error", message)
+		return nil
+	}
+	return w.writeEvent("error", gin.H{
+		"type":  "error",
+		"error": gin.H{"type": "api_error", "message": message},
+	})
+}
+
+func (w *AnthropicWriter) Committed() bool {
+	return w.gc.Writer.Written()
+}
+
+func (w *AnthropicWriter) Text() string {
+	// 暂存的尾部没有命中 stop sequence，Finish 时会原样输出
+	return w.text.String() + w.pending
+}
+
+func (w *AnthropicWriter) SetUsage(usage Usage) {
+	w.usage = usage
+}
+
+func (w *AnthropicWriter) Finish() error {
+	w.flushPending()
+
+	stopReason := "end_turn"
+	var stopSequence *string
+	if w.stopped {
+		stopReason = "stop_sequence"
+		stopSequence = &w.stopSequence
+	} else if len(w.toolUses) > 0 {
+		stopReason = "tool_use"
+	}
+
+	if !w.stream {
+		content := make([]interface{}, 0, len(w.toolUses)+2)
+		if w.thinking.Len() > 0 {
+			content = append(content, gin.H{"type": "thinking", "thinking": w.thinking.String(), "signature": ""})
+		}
+		if w.text.Len() > 0 || len(w.toolUses) == 0 {
+			content = append(content, AnthropicContentBlock{Type: "text", Text: w.text.String()})
+		}
+		for _, block := range w.toolUses {
+			content = append(content, block)
+		}
+		w.gc.JSON(200, &AnthropicResponse{
+			ID:           w.id,
+			Type:         "message",
+			Role:         "assistant",
+			Model:        w.model,
+			Content:      content,
+			StopReason:   &stopReason,
+			StopSequence: stopSequence,
+			Usage: AnthropicUsage{
+				InputTokens:  w.usage.PromptTokens,
+				OutputTokens: w.usage.CompletionTokens,
+			},
+		})
+		return nil
+	}
+
+	// 没有任何内容块时补一个空文本块
+	if w.blockIndex == 0 && w.openBlock == "" {
+		w.startBlock("text")
+	}
+	w.closeBlock()
+	w.writeEvent("message_delta", gin.H{
+		"type":  "message_delta",
+		"delta": gin.H{"stop_reason": stopReason, "stop_sequence": stopSequence},
+		"usage": gin.H{"output_tokens": w.usage.CompletionTokens},
+	})
+	return w.writeEvent("message_stop", gin.H{"type": "message_stop"})
+}
+
+// flushPending 输出因 stop sequence 检测而暂存的尾部文本
+func (w *AnthropicWriter) flushPending() {
+	if !w.stopped && w.pending != "" {
+		w.emit(w.pending)
+		w.pending = ""
+	}
+}
+
+// emit 输出已确认的正文
+func (w *AnthropicWriter) emit(text string) error {
+	w.text.WriteString(text)
+	if !w.stream || text == "" {
+		return nil
+	}
+	w.startBlock("text")
+	return w.writeEvent("content_block_delta", gin.H{
+		"type":  "content_block_delta",
+		"index": w.blockIndex,
+		"delta": gin.H{"type": "text_delta", "text": text},
+	})
+}
+
+// start 发送 message_start（仅一次）
+func (w *AnthropicWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.writeEvent("message_start", gin.H{
+		"type": "message_start",
+		"message": &AnthropicResponse{
+			ID:      w.id,
+			Type:    "message",
+			Role:    "assistant",
+			Model:   w.model,
+			Content: []interface{}{},
+			// 输入 token 在请求开始前已估算
+			Usage: AnthropicUsage{InputTokens: w.gc.GetInt("PromptTokens")},
+		},
+	})
+}
+
+// startBlock 开始 text 或 thinking 内容块，必要时先结束另一类内容块
+func (w *AnthropicWriter) startBlock(kind string) {
+	if w.openBlock == kind {
+		return
+	}
+	w.closeBlock()
+	w.start()
+	w.openBlock = kind
+	var block interface{} = AnthropicContentBlock{Type: "text"}
+	if kind == "thinking" {
+		block = gin.H{"type": "thinking", "thinking": ""}
+	}
+	w.writeEvent("content_block_start", gin.H{
+		"type":          "content_block_start",
+		"index":         w.blockIndex,
+		"content_block": block,
+	})
+}
+
+// closeBlock 结束当前内容块
+func (w *AnthropicWriter) closeBlock() {
+	if w.openBlock == "" {
+		return
+	}
+	w.openBlock = ""
+	w.writeEvent("content_block_stop", gin.H{"type": "content_block_stop", "index": w.blockIndex})
+	w.blockIndex++
+}
+
+func (w *AnthropicWriter) writeEvent(event string, data interface{}) error {
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error marshalling JSON: %v", err))
+		return err
+	}
+	StartStream(w.gc)
+	fmt.Fprintf(w.gc.Writer, "event: %s\ndata: %s\n\n", event, jsonBytes)
+	w.gc.Writer.Flush()
+	return nil
+}
+
+// ReturnAnthropicError 以 Anthropic 错误格式返回
+func ReturnAnthropicError(gc *gin.Context, status int, errType string, message string) {
+	gc.JSON(status, gin.H{
+		"type": "error",
+		"error": gin.H{
+			"type":    errType,
+			"message": message,
+		},
+	})
+}
@@ -14,7 +14,16 @@ type ChatCompletionRequest struct {
 	Model    string                   `json:"model"`
 	Messages []map[string]interface{} `json:"messages"`
 	Stream   bool                     `json:"stream"`
-	Tools    []map[string]interface{} `json:"tools,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Tools      []map[string]interface{} `json:"tools,omitempty"`
+	ToolChoice interface{}              `json:"tool_choice,omitempty"`
+	// ThinkingMode 覆盖全局 thinkingMode：inline、reasoning_content 或 drop
+	ThinkingMode string `json:"thinking_mode,omitempty"`
+}
+
+// StreamOptions 流式选项，include_usage 时在结束前额外发送 usage 块
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // OpenAISrteamResponse 定义 OpenAI 的流式响应结构
@@ -24,6 +33,7 @@ type OpenAISrteamResponse struct {
 	Created int64          `json:"created"`
 	Model   string         `json:"model"`
 	Choices []StreamChoice `json:"choices"`
+	Usage   *Usage         `json:"usage,omitempty"`
 }
 
 // Choice 结构表示 OpenAI 返回的单个选项
@@ -42,16 +52,35 @@ type NoStreamChoice struct {
 }
 
 // Delta 结构用于存储返回的文本内容
+// Content 为 nil 时省略（仅工具调用块），其余块即使为空也输出 "content":""
 type Delta struct {
-	Content string `json:"content"`
+	Role             string     `json:"role,omitempty"`
+	Content          *string    `json:"content,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
 }
 type Message struct {
-	Role       string        `json:"role"`
-	Content    string        `json:"content"`
+	Role             string        `json:"role"`
+	Content          string        `json:"content"`
+	ReasoningContent string        `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall    `json:"tool_calls,omitempty"`
 	Refusal    interface{}   `json:"refusal"`
 	Annotation []interface{} `json:"annotation"`
 }
 
+// ToolCall OpenAI 格式的工具调用
+type ToolCall struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
 type OpenAIResponse struct {
 	ID      string           `json:"id"`
 	Object  string           `json:"object"`
@@ -75,23 +104,45 @@ func ReturnOpenAIResponse(text string, stream bool, gc *gin.Context) error {
 }
 
 func streamRespose(text string, gc *gin.Context) error {
-	openAIResp := &OpenAISrteamResponse{
+	return writeStreamChunk(textDelta(text), nil, gc)
+}
+
+// textDelta 构造正文增量块
+func textDelta(text string) Delta {
+	return Delta{Content: &text}
+}
+
+// writeStreamChunk 发送一个 chat.completion.chunk
+func writeStreamChunk(delta Delta, finishReason interface{}, gc *gin.Context) error {
+	return writeStreamResponse(&OpenAISrteamResponse{
 		ID:      uuid.New().String(),
 		Object:  "chat.completion.chunk",
 		Created: time.Now().Unix(),
 		Model:   "claude-3-7-sonnet-20250219",
 		Choices: []StreamChoice{
 			{
-				Index: 0,
-				Delta: Delta{
-					Content: text,
-				},
+				Index:        0,
+				Delta:        delta,
 				Logprobs:     nil,
-				FinishReason: nil,
+				FinishReason: finishReason,
 			},
 		},
-	}
+	}, gc)
+}
+
+// writeUsageChunk 发送 stream_options.include_usage 要求的 usage 块（choices 为空）
+func writeUsageChunk(usage Usage, gc *gin.Context) error {
+	return writeStreamResponse(&OpenAISrteamResponse{
+		ID:      uuid.New().String(),
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   "claude-3-7-sonnet-20250219",
+		Choices: []StreamChoice{},
+		Usage:   &usage,
+	}, gc)
+}
 
+func writeStreamResponse(openAIResp *OpenAISrteamResponse, gc *gin.Context) error {
 	jsonBytes, err := json.Marshal(openAIResp)
 	jsonBytes = append([]byte("data: "), jsonBytes...)
 	jsonBytes = append(jsonBytes, []byte("\n\n")...)
@@ -101,12 +152,18 @@ func streamRespose(text string, gc *gin.Context) error {
 	}
 
 	// 发送数据
+	StartStream(gc)
 	gc.Writer.Write(jsonBytes)
 	gc.Writer.Flush()
 	return nil
 }
 
 func noStreamResponse(text string, gc *gin.Context) error {
+	return writeNoStreamResponse(Message{Role: "assistant", Content: text}, "stop", Usage{}, gc)
+}
+
+// writeNoStreamResponse 返回完整的 chat.completion
+func writeNoStreamResponse(message Message, finishReason string, usage Usage, gc *gin.Context) error {
 	openAIResp := &OpenAIResponse{
 		ID:      uuid.New().String(),
 		Object:  "chat.completion",
@@ -115,14 +172,12 @@ func noStreamResponse(text string, gc *gin.Context) error {
 		Choices: []NoStreamChoice{
 			{
 				Index: 0,
-				Message: Message{
-					Role:    "assistant",
-					Content: text,
-				},
+				Message:      message,
 				Logprobs:     nil,
-				FinishReason: "stop",
+				FinishReason: finishReason,
 			},
 		},
+		Usage: usage,
 	}
 
 	gc.JSON(200, openAIResp)
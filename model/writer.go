@@ -0,0 +1,155 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrResponseCommitted 响应已开始写给客户端后上游失败，不能再换 session 重试
+var ErrResponseCommitted = errors.New("response already committed to client")
+
+// 响应格式，通过 gin.Context 的 ResponseFormat 键选择
+const (
+	FormatOpenAI    = "openai"
+	FormatAnthropic = "anthropic"
+)
+
+// ResponseWriter 把 Claude 的增量输出写成客户端需要的 API 格式
+type ResponseWriter interface {
+	// WriteText 写入一段正文增量（非流式时仅累积）
+	WriteText(text string) error
+	// WriteReasoning 写入一段思考内容增量（reasoning_content 模式）
+	WriteReasoning(text string) error
+	// WriteToolCalls 写入从回复中解析出的工具调用，结束原因随之变为工具调用
+	WriteToolCalls(calls []ToolCall) error
+	// WriteError 在响应已开始后写入错误并结束响应（流式为错误事件）
+	WriteError(message string) error
+	// Committed 是否已有数据写给客户端，之后失败不能再重试
+	Committed() bool
+	// Text 返回实际输出给客户端的正文，已按 stop_sequences 截断，包括将在 Finish 时输出的暂存部分
+	Text() string
+	// SetUsage 设置 Finish 时上报的 token 用量
+	SetUsage(usage Usage)
+	// Finish 结束响应：流式发送结束事件，非流式输出完整结果
+	Finish() error
+}
+
+// NewResponseWriter 根据请求上下文中的 ResponseFormat 创建对应的 ResponseWriter
+func NewResponseWriter(gc *gin.Context, stream bool) ResponseWriter {
+	if gc.GetString("ResponseFormat") == FormatAnthropic {
+		return NewAnthropicWriter(gc, stream, gc.GetString("RequestModel"), gc.GetStringSlice("StopSequences"))
+	}
+	return NewOpenAIWriter(gc, stream, gc.GetBool("IncludeUsage"))
+}
+
+// StartStream 发送流式响应头（仅一次），之后响应即视为已提交
+func StartStream(gc *gin.Context) {
+	if gc.Writer.Written() {
+		return
+	}
+	gc.Writer.Header().Set("Content-Type", "text/event-stream")
+	gc.Writer.Header().Set("Cache-Control", "no-cache")
+	gc.Writer.Header().Set("Connection", "keep-alive")
+	gc.Writer.WriteHeader(http.StatusOK)
+	gc.Writer.Flush()
+}
+
+// OpenAIWriter 输出 OpenAI chat.completion 格式
+type OpenAIWriter struct {
+	gc           *gin.Context
+	stream       bool
+	includeUsage bool
+	usage        Usage
+	allText      string
+	reasoning    string
+	toolCalls    []ToolCall
+}
+
+// NewOpenAIWriter creates a writer for the OpenAI chat completions format
+// includeUsage 对应 stream_options.include_usage，仅影响流式输出
+func NewOpenAIWriter(gc *gin.Context, stream bool, includeUsage bool) *OpenAIWriter {
+	return &OpenAIWriter{gc: gc, stream: stream, includeUsage: includeUsage}
+}
+
+func (w *OpenAIWriter) WriteText(text string) error {
+	w.allText += text
+	if !w.stream {
+		return nil
+	}
+	return ReturnOpenAIResponse(text, true, w.gc)
+}
+
+func (w *OpenAIWriter) WriteReasoning(text string) error {
+	w.reasoning += text
+	if !w.stream {
+		return nil
+	}
+	return writeStreamChunk(Delta{Content: new(string), ReasoningContent: text}, nil, w.gc)
+}
+
+func (w *OpenAIWriter) WriteToolCalls(calls []ToolCall) error {
+	w.toolCalls = append(w.toolCalls, calls...)
+	if !w.stream {
+		return nil
+	}
+	return writeStreamChunk(Delta{Role: "assistant", ToolCalls: calls}, nil, w.gc)
+}
+
+func (w *OpenAIWriter) WriteError(message string) error {
+	errBody := gin.H{"error": gin.H{"message": message, "type": "api_error"}}
+	if !w.stream {
+		w.gc.JSON(http.StatusBadGateway, errBody)
+		return nil
+	}
+	jsonBytes, err := json.Marshal(errBody)
+	if err != nil {
+		return err
+	}
+	StartStream(w.gc)
+	w.gc.Writer.Write([]byte("data: " + string(jsonBytes) + "\n\n"))
+	w.gc.Writer.Write([]byte("data: [DONE]\n\n"))
+	w.gc.Writer.Flush()
+	return nil
+}
+
+func (w *OpenAIWriter) Committed() bool {
+	return w.gc.Writer.Written()
+}
+
+func (w *OpenAIWriter) Text() string {
+	return w.allText
+}
+
+func (w *OpenAIWriter) SetUsage(usage Usage) {
+	w.usage = usage
+}
+
+func (w *OpenAIWriter) Finish() error {
+	finishReason := "stop"
+	if len(w.toolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+	if !w.stream {
+		return writeNoStreamResponse(Message{
+			Role:             "assistant",
+			Content:          w.allText,
+			ReasoningContent: w.reasoning,
+			ToolCalls:        w.toolCalls,
+		}, finishReason, w.usage, w.gc)
+	}
+	if err := writeStreamChunk(textDelta(""), finishReason, w.gc); err != nil {
+		return err
+	}
+	if w.includeUsage {
+		if err := writeUsageChunk(w.usage, w.gc); err != nil {
+			return err
+		}
+	}
+	// 发送结束标志
+	w.gc.Writer.Write([]byte("data: [DONE]\n\n"))
+	w.gc.Writer.Flush()
+	return nil
+}
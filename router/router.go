@@ -14,49 +14,92 @@ func SetupRoutes(r *gin.Engine) {
     
     // Public routes (no authentication)
     r.GET("/health", service.HealthCheckHandler)
-    r.POST("/admin/login", service.AdminLoginHandler)
-    r.POST("/admin/logout", service.AdminLogoutHandler)
+    r.POST("/admin/login", middleware.AdminClientCertMiddleware(), service.AdminLoginHandler)
+    r.POST("/admin/refresh", middleware.AdminClientCertMiddleware(), service.AdminRefreshHandler)
+    r.POST("/admin/logout", middleware.AdminClientCertMiddleware(), service.AdminLogoutHandler)
 
     // API routes (API key authentication)
     api := r.Group("/v1")
-    api.Use(middleware.AuthMiddleware())
+    api.Use(middleware.AuthMiddleware(), middleware.RateLimitMiddleware())
     {
         api.POST("/chat/completions", service.ChatCompletionsHandler)
+        api.POST("/messages", service.MessagesHandler)
         api.GET("/models", service.ModelsHandler)
     }
 
-    // WebSocket (require API key)
-    r.GET("/ws", middleware.AuthMiddleware(), service.WebSocketHandler)
+    // WebSocket (admin token with sessions:read, passed as ?token= by browsers)
+    r.GET("/ws", middleware.AdminAuthMiddleware(),
+        middleware.RequireAdminAccess(config.AdminRoleViewer, config.AdminScopeSessionsRead), service.WebSocketHandler)
 
     // Admin routes (JWT authentication)
     admin := r.Group("/admin")
     admin.Use(middleware.AdminAuthMiddleware())
     {
-        admin.GET("/sessions", service.SessionsHealthHandler)
-        admin.POST("/sessions", service.AddSessionHandler)
-        admin.GET("/sessions/:key", service.SessionDetailHandler)
-        admin.PUT("/sessions/:key", service.UpdateSessionHandler)
-        admin.DELETE("/sessions/:key", service.DeleteSessionHandler)
-        admin.POST("/sessions/:key/reset", service.ResetSessionHandler)
-        admin.GET("/stats", service.SessionStatsHandler)
-        admin.GET("/config", service.ConfigHandler)
-        admin.PUT("/config", service.UpdateConfigHandler)
-        admin.GET("/me", service.GetAdminInfoHandler)
+        // 账号角色（viewer < operator < admin）与管理API token的权限范围，权限范围为空的接口只对账号开放
+        sessionsRead := middleware.RequireAdminAccess(config.AdminRoleViewer, config.AdminScopeSessionsRead)
+        sessionsWrite := middleware.RequireAdminAccess(config.AdminRoleOperator, config.AdminScopeSessionsWrite)
+        sessionsExport := middleware.RequireAdminAccess(config.AdminRoleOperator, config.AdminScopeSessionsRead)
+        configRead := middleware.RequireAdminAccess(config.AdminRoleViewer, config.AdminScopeConfigRead)
+        configWrite := middleware.RequireAdminAccess(config.AdminRoleAdmin, config.AdminScopeConfigWrite)
+        apiKeysRead := middleware.RequireAdminAccess(config.AdminRoleViewer, config.AdminScopeAPIKeysRead)
+        apiKeysWrite := middleware.RequireAdminAccess(config.AdminRoleAdmin, config.AdminScopeAPIKeysWrite)
+        account := middleware.RequireAdminAccess(config.AdminRoleViewer, "")
+        accountsManage := middleware.RequireAdminAccess(config.AdminRoleAdmin, "")
+
+        admin.GET("/sessions", sessionsRead, service.SessionsHealthHandler)
+        admin.GET("/sessions/:key", sessionsRead, service.SessionDetailHandler)
+        admin.GET("/stats", sessionsRead, service.SessionStatsHandler)
+        admin.POST("/sessions", sessionsWrite, service.AddSessionHandler)
+        admin.POST("/sessions/test", sessionsWrite, service.TestSessionHandler)
+        admin.POST("/sessions/import", sessionsWrite, service.ImportSessionsHandler)
+        admin.GET("/sessions/export", sessionsExport, service.ExportSessionsHandler)
+        admin.PUT("/sessions/:key", sessionsWrite, service.UpdateSessionHandler)
+        admin.DELETE("/sessions/:key", sessionsWrite, service.DeleteSessionHandler)
+        admin.POST("/sessions/:key/reset", sessionsWrite, service.ResetSessionHandler)
+        admin.POST("/sessions/:key/enable", sessionsWrite, service.EnableSessionHandler)
+        admin.POST("/sessions/:key/disable", sessionsWrite, service.DisableSessionHandler)
+        admin.POST("/sessions/:key/drain", sessionsWrite, service.DrainSessionHandler)
+        admin.POST("/sessions/:key/cooldown", sessionsWrite, service.CooldownSessionHandler)
+
+        admin.GET("/config", configRead, service.ConfigHandler)
+        admin.PUT("/config", configWrite, service.UpdateConfigHandler)
+        admin.PATCH("/config", configWrite, service.UpdateConfigHandler)
+        admin.POST("/config/reload", configWrite, service.ReloadConfigHandler)
+
+        admin.GET("/api-keys", apiKeysRead, service.ListAPIKeysHandler)
+        admin.GET("/api-keys/:id", apiKeysRead, service.GetAPIKeyHandler)
+        admin.POST("/api-keys", apiKeysWrite, service.CreateAPIKeyHandler)
+        admin.POST("/api-keys/:id/revoke", apiKeysWrite, service.RevokeAPIKeyHandler)
+        admin.POST("/api-keys/:id/rotate", apiKeysWrite, service.RotateAPIKeyHandler)
+
+        admin.GET("/me", account, service.GetAdminInfoHandler)
+        admin.POST("/me/password", account, service.ChangeOwnPasswordHandler)
+        admin.GET("/me/sessions", account, service.ListAdminLoginsHandler)
+        admin.POST("/logout/all", account, service.AdminLogoutAllHandler)
+        admin.GET("/users", accountsManage, service.ListAdminUsersHandler)
+        admin.POST("/users", accountsManage, service.CreateAdminUserHandler)
+        admin.PUT("/users/:username", accountsManage, service.UpdateAdminUserHandler)
+        admin.DELETE("/users/:username", accountsManage, service.DeleteAdminUserHandler)
+        admin.POST("/users/:username/password", accountsManage, service.ResetAdminUserPasswordHandler)
+        admin.GET("/tokens", accountsManage, service.ListAdminAPITokensHandler)
+        admin.POST("/tokens", accountsManage, service.CreateAdminAPITokenHandler)
+        admin.POST("/tokens/:id/revoke", accountsManage, service.RevokeAdminAPITokenHandler)
     }
-
-	
+
+	
     if config.ConfigInstance.EnableMirrorApi {
-        r.POST(config.ConfigInstance.MirrorApiPrefix+"/v1/chat/completions", service.MirrorChatHandler)
+        r.POST(config.ConfigInstance.MirrorApiPrefix+"/v1/chat/completions", middleware.RateLimitMiddleware(), service.MirrorChatHandler)
         r.GET(config.ConfigInstance.MirrorApiPrefix+"/v1/models", service.ModelsHandler)
     }
-
-	// HuggingFace compatible routes
-	hfRouter := r.Group("/hf")
-	{
-		v1Router := hfRouter.Group("/v1")
-		{
-			v1Router.POST("/chat/completions", service.ChatCompletionsHandler)
-			v1Router.GET("/models", service.ModelsHandler)
-		}
-	}
-}
+
+	// HuggingFace compatible routes
+	hfRouter := r.Group("/hf")
+	hfRouter.Use(middleware.RateLimitMiddleware())
+	{
+		v1Router := hfRouter.Group("/v1")
+		{
+			v1Router.POST("/chat/completions", service.ChatCompletionsHandler)
+			v1Router.GET("/models", service.ModelsHandler)
+		}
+	}
+}
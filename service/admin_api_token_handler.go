@@ -0,0 +1,109 @@
+package service
+
+import (
+	"claude2api/config"
+	"claude2api/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAdminAPITokensHandler 列出管理API token，不返回明文
+func ListAdminAPITokensHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": config.ConfigInstance.GetAdminAPITokenStore().List(),
+		"scopes": config.AdminScopes,
+	})
+}
+
+// CreateAdminAPITokenHandler 创建管理API token，明文只在响应中返回一次
+// expiresAt 为 RFC 3339 时间，也可以用 expiresIn 指定秒数或 "720h" 形式的有效期，都不指定时不过期
+func CreateAdminAPITokenHandler(c *gin.Context) {
+	var req struct {
+		Name      string          `json:"name"`
+		Scopes    []string        `json:"scopes"`
+		ExpiresAt *time.Time      `json:"expiresAt"`
+		ExpiresIn json.RawMessage `json:"expiresIn"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	spec := config.AdminAPITokenSpec{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		CreatedBy: c.GetString("admin_user"),
+	}
+	if req.ExpiresAt != nil {
+		spec.ExpiresAt = *req.ExpiresAt
+	}
+	if req.ExpiresIn != nil && string(req.ExpiresIn) != "null" {
+		expiresIn, ok := parseJSONDuration(req.ExpiresIn)
+		if !ok || expiresIn <= 0 {
+			errs := &config.ValidationError{}
+			errs.Add("expiresIn", "must be a positive number of seconds or a duration such as 720h")
+			writeValidationError(c, "Invalid admin API token", errs)
+			return
+		}
+		spec.ExpiresAt = time.Now().Add(expiresIn)
+	}
+
+	token, secret, err := config.ConfigInstance.GetAdminAPITokenStore().Create(spec)
+	if token == nil {
+		var validationErr *config.ValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(c, "Invalid admin API token", err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to create admin API token: %v", err),
+		})
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Admin API token %s (%s) with scopes %v created by %s", token.ID, token.Name, token.Scopes, token.CreatedBy))
+	c.JSON(http.StatusCreated, withAdminAPITokenSaveWarning(gin.H{
+		"message": "Admin API token created successfully, store the token now as it will not be shown again",
+		"token":   token,
+		"secret":  secret,
+	}, err))
+}
+
+// RevokeAdminAPITokenHandler 吊销管理API token，立即失效
+func RevokeAdminAPITokenHandler(c *gin.Context) {
+	token, err := config.ConfigInstance.GetAdminAPITokenStore().Revoke(c.Param("id"))
+	if token == nil {
+		if errors.Is(err, config.ErrAdminAPITokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Admin API token not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Admin API token %s (%s) revoked by %s", token.ID, token.Name, c.GetString("admin_user")))
+	c.JSON(http.StatusOK, withAdminAPITokenSaveWarning(gin.H{
+		"message": "Admin API token revoked successfully",
+		"token":   token,
+	}, err))
+}
+
+// withAdminAPITokenSaveWarning token文件写回失败时修改仍在内存中生效，在响应中附带警告
+func withAdminAPITokenSaveWarning(resp gin.H, err error) gin.H {
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to save admin api tokens: %v", err))
+		resp["warning"] = "Change applied but not saved to disk: " + err.Error()
+	}
+	return resp
+}
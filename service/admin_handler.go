@@ -2,7 +2,11 @@ package service
 
 import (
 	"claude2api/config"
+	"claude2api/core"
+	"claude2api/logger"
 	"claude2api/middleware"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
@@ -53,6 +57,11 @@ func SessionsHealthHandler(c *gin.Context) {
 
 	sessionManager := config.ConfigInstance.GetSessionManager()
 	sessions := sessionManager.GetSessionsHealth()
+	if !canReadSessionKeys(c) {
+		for _, session := range sessions {
+			maskSessionHealth(session)
+		}
+	}
 
 	c.JSON(http.StatusOK, sessions)
 }
@@ -82,6 +91,8 @@ func SessionStatsHandler(c *gin.Context) {
 		"uptime":           formatDuration(time.Since(config.ConfigInstance.GetSessionManager().GetStartTime())),
 		"last_reset":        stats.LastReset,
 		"errors_by_type":    stats.ErrorsByType,
+		"client_pool":       sessionManager.GetClientPoolStats(),
+		"api_keys":          apiKeyUsage(),
 	}
 
 	c.JSON(http.StatusOK, systemStats)
@@ -108,7 +119,10 @@ func SessionDetailHandler(c *gin.Context) {
 	sessions := sessionManager.GetSessionsHealth()
 
 	for _, session := range sessions {
-		if session.SessionKey == sessionKey {
+		if session.SessionKey == sessionKey || session.ID == sessionKey {
+			if !canReadSessionKeys(c) {
+				maskSessionHealth(session)
+			}
 			c.JSON(http.StatusOK, session)
 			return
 		}
@@ -119,9 +133,77 @@ func SessionDetailHandler(c *gin.Context) {
 	})
 }
 
-// ResetSessionHandler 重置特定session的状态
+// canReadSessionKeys 完整的sessionKey即 claude.ai 的登录凭据，只对 admin 角色或具有 sessions:export 权限范围的token可见
+func canReadSessionKeys(c *gin.Context) bool {
+	return middleware.HasAdminAccess(c, config.AdminRoleAdmin, config.AdminScopeSessionsExport)
+}
+
+// maskSessionHealth 脱敏健康状态副本中的sessionKey，调用方通过 id 引用session
+func maskSessionHealth(session *config.SessionHealth) *config.SessionHealth {
+	session.SessionKey = logger.MaskSecret(session.SessionKey)
+	return session
+}
+
+// resolveSessionKey 路径参数可以是sessionKey或 SessionID，找不到对应的session时原样返回
+func resolveSessionKey(param string) string {
+	config.ConfigInstance.RwMutx.RLock()
+	defer config.ConfigInstance.RwMutx.RUnlock()
+	for _, session := range config.ConfigInstance.Sessions {
+		if session.SessionKey == param || config.SessionID(session.SessionKey) == param {
+			return session.SessionKey
+		}
+	}
+	return param
+}
+
+// ResetSessionHandler 重置特定session的状态：清空计数、冷却与熔断
 func ResetSessionHandler(c *gin.Context) {
-	sessionKey := c.Param("key")
+	handleSessionLifecycle(c, "reset", (*config.SessionManager).ResetSession)
+}
+
+// EnableSessionHandler 重新启用停用或排空中的session
+func EnableSessionHandler(c *gin.Context) {
+	handleSessionLifecycle(c, "enabled", (*config.SessionManager).EnableSession)
+}
+
+// DisableSessionHandler 停用session，立即退出调度
+func DisableSessionHandler(c *gin.Context) {
+	handleSessionLifecycle(c, "disabled", (*config.SessionManager).DisableSession)
+}
+
+// DrainSessionHandler 排空session，进行中的请求完成后停用
+func DrainSessionHandler(c *gin.Context) {
+	handleSessionLifecycle(c, "drained", (*config.SessionManager).DrainSession)
+}
+
+// CooldownSessionHandler 强制session冷却，duration 为秒数或 duration 字符串
+func CooldownSessionHandler(c *gin.Context) {
+	var req struct {
+		Duration json.RawMessage `json:"duration"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+	duration, ok := parseJSONDuration(req.Duration)
+	if !ok || duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request format",
+			"fields": []config.FieldError{{Field: "duration", Message: "must be a positive number of seconds or a duration such as 10m"}},
+		})
+		return
+	}
+
+	handleSessionLifecycle(c, "cooled_down", func(sm *config.SessionManager, sessionKey string) (*config.SessionHealth, error) {
+		return sm.ForceCooldown(sessionKey, duration)
+	})
+}
+
+// handleSessionLifecycle 对session执行生命周期操作，返回操作后的状态并广播变化
+func handleSessionLifecycle(c *gin.Context, action string, operation func(sm *config.SessionManager, sessionKey string) (*config.SessionHealth, error)) {
+	sessionKey := resolveSessionKey(c.Param("key"))
 	if sessionKey == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Session key is required",
@@ -136,19 +218,49 @@ func ResetSessionHandler(c *gin.Context) {
 		return
 	}
 
-	// 这里可以实现重置session状态的逻辑
-	// 目前只是返回成功响应
+	session, err := operation(config.ConfigInstance.GetSessionManager(), sessionKey)
+	if errors.Is(err, config.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Session %s %s by admin, status: %s", logger.MaskSecret(sessionKey), action, session.Status))
+
+	// 广播WebSocket消息
+	if WebSocketServiceInstance != nil {
+		WebSocketServiceInstance.BroadcastSessionChange(sessionKey, action)
+		WebSocketServiceInstance.BroadcastSessions()
+	}
+
+	if !canReadSessionKeys(c) {
+		maskSessionHealth(session)
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Session reset request received",
-		"session": sessionKey,
+		"message": fmt.Sprintf("Session %s successfully", strings.ReplaceAll(action, "_", " ")),
+		"session": session,
+		"status":  session.Status.String(),
 	})
 }
 
 // AddSessionHandler 添加新的Session
 func AddSessionHandler(c *gin.Context) {
 	var req struct {
-		SessionKey string `json:"sessionKey" binding:"required"`
-		OrgID      string `json:"orgID"`
+		SessionKey    string   `json:"sessionKey" binding:"required"`
+		OrgID         string   `json:"orgID"`
+		Tags          []string `json:"tags"`
+		Tier          string   `json:"tier"`
+		AllowedModels []string `json:"allowedModels"`
+		MaxConcurrent int      `json:"maxConcurrent"`
+		// Verify 是否先向上游验证sessionKey，默认验证
+		Verify *bool `json:"verify"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -161,7 +273,8 @@ func AddSessionHandler(c *gin.Context) {
 	// 验证Session Key格式
 	if !isValidSessionKey(req.SessionKey) {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid session key format. Session key should start with 'sk-' or 'sk-ant-'",
+			"error":  "Invalid session key format. Session key should start with 'sk-' or 'sk-ant-'",
+			"reason": "format",
 		})
 		return
 	}
@@ -179,11 +292,44 @@ func AddSessionHandler(c *gin.Context) {
 	}
 	config.ConfigInstance.RwMutx.RUnlock()
 
-	// 添加新Session到Config
-	config.ConfigInstance.RwMutx.Lock()
 	newSession := config.SessionInfo{
-		SessionKey: req.SessionKey,
-		OrgID:      req.OrgID,
+		SessionKey:    req.SessionKey,
+		OrgID:         req.OrgID,
+		Tags:          req.Tags,
+		Tier:          req.Tier,
+		AllowedModels: req.AllowedModels,
+		MaxConcurrent: req.MaxConcurrent,
+	}
+	if err := newSession.Validate(); err != nil {
+		writeValidationError(c, "Invalid session", err)
+		return
+	}
+
+	// 向上游验证sessionKey，自动填充orgID
+	var verification *sessionKeyVerification
+	if req.Verify == nil || *req.Verify {
+		verification = verifySessionKey(req.SessionKey, req.OrgID)
+		if !verification.Valid {
+			c.JSON(verification.httpStatus(), gin.H{
+				"error":        "Session key verification failed",
+				"reason":       verification.Reason,
+				"verification": verification,
+			})
+			return
+		}
+		newSession.OrgID = verification.OrgID
+	}
+
+	// 添加新Session到Config，验证期间可能已通过其他请求添加
+	config.ConfigInstance.RwMutx.Lock()
+	for _, session := range config.ConfigInstance.Sessions {
+		if session.SessionKey == newSession.SessionKey {
+			config.ConfigInstance.RwMutx.Unlock()
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Session already exists",
+			})
+			return
+		}
 	}
 	config.ConfigInstance.Sessions = append(config.ConfigInstance.Sessions, newSession)
 	config.ConfigInstance.RwMutx.Unlock()
@@ -193,6 +339,9 @@ func AddSessionHandler(c *gin.Context) {
 		sessionManager := config.ConfigInstance.GetSessionManager()
 		if sessionManager != nil {
 			sessionManager.AddSession(newSession)
+			if verification != nil {
+				sessionManager.UpdateOrganization(newSession.SessionKey, verification.OrgID, verification.RateLimitTier)
+			}
 		}
 	}
 
@@ -202,15 +351,101 @@ func AddSessionHandler(c *gin.Context) {
 		WebSocketServiceInstance.BroadcastSessions()
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	resp := gin.H{
 		"message": "Session added successfully",
 		"session": newSession,
-	})
+	}
+	if verification != nil {
+		resp["verification"] = verification
+	}
+	c.JSON(http.StatusCreated, persistConfig(resp))
+}
+
+// TestSessionHandler 向上游验证sessionKey但不添加，用于添加前检查
+func TestSessionHandler(c *gin.Context) {
+	var req struct {
+		SessionKey string `json:"sessionKey" binding:"required"`
+		OrgID      string `json:"orgID"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	if !isValidSessionKey(req.SessionKey) {
+		c.JSON(http.StatusOK, &sessionKeyVerification{
+			Reason:  "format",
+			Message: "session key should start with 'sk-' or 'sk-ant-'",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, verifySessionKey(req.SessionKey, req.OrgID))
+}
+
+// sessionKeyVerification sessionKey的验证结果
+type sessionKeyVerification struct {
+	Valid         bool   `json:"valid"`
+	OrgID         string `json:"orgID,omitempty"`
+	RateLimitTier string `json:"rateLimitTier,omitempty"`
+	// Reason 验证失败的原因：format、auth、rate_limit、server、network、timeout、other 或 org_not_found
+	Reason string `json:"reason,omitempty"`
+	// Message 面向用户的失败说明，Detail 为上游返回的原始错误
+	Message   string `json:"message,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// httpStatus 验证失败时的状态码：sessionKey本身的问题返回 422，上游不可用返回 502
+func (v *sessionKeyVerification) httpStatus() int {
+	switch v.Reason {
+	case config.ErrorRateLimit.String(), config.ErrorServer.String(), config.ErrorNetwork.String(), config.ErrorTimeout.String():
+		return http.StatusBadGateway
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// verifySessionKey 通过账户与组织接口验证sessionKey，不消耗消息额度；指定的orgID必须属于该账户
+func verifySessionKey(sessionKey, orgID string) *sessionKeyVerification {
+	client := core.NewClient(sessionKey, config.ConfigInstance.Proxy)
+	defer client.Close()
+
+	result := client.Probe(orgID)
+	verification := &sessionKeyVerification{LatencyMs: result.Latency.Milliseconds()}
+	switch {
+	case result.Err != nil:
+		verification.Reason = result.ErrorType.String()
+		verification.Detail = result.Err.Error()
+		switch result.ErrorType {
+		case config.ErrorAuth:
+			verification.Message = "session key is invalid, expired or revoked"
+		case config.ErrorRateLimit:
+			verification.Message = "claude.ai rate limited the verification request, try again later"
+		case config.ErrorServer:
+			verification.Message = "claude.ai returned a server error, try again later"
+		case config.ErrorNetwork, config.ErrorTimeout:
+			verification.Message = "could not reach claude.ai, check the proxy settings"
+		default:
+			verification.Message = "session key could not be verified: " + result.Err.Error()
+		}
+	case orgID != "" && result.OrgID != orgID:
+		verification.Reason = "org_not_found"
+		verification.Message = fmt.Sprintf("organization %s is not accessible with this session key", orgID)
+	default:
+		verification.Valid = true
+		verification.OrgID = result.OrgID
+		verification.RateLimitTier = result.RateLimitTier
+	}
+	return verification
 }
 
 // DeleteSessionHandler 删除Session
 func DeleteSessionHandler(c *gin.Context) {
-	sessionKey := c.Param("key")
+	sessionKey := resolveSessionKey(c.Param("key"))
 	if sessionKey == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Session key is required",
@@ -219,8 +454,6 @@ func DeleteSessionHandler(c *gin.Context) {
 	}
 
 	config.ConfigInstance.RwMutx.Lock()
-	defer config.ConfigInstance.RwMutx.Unlock()
-
 	// 查找并删除Session
 	found := false
 	for i, session := range config.ConfigInstance.Sessions {
@@ -230,6 +463,7 @@ func DeleteSessionHandler(c *gin.Context) {
 			break
 		}
 	}
+	config.ConfigInstance.RwMutx.Unlock()
 
 	if !found {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -252,14 +486,14 @@ func DeleteSessionHandler(c *gin.Context) {
 		WebSocketServiceInstance.BroadcastSessions()
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusOK, persistConfig(gin.H{
 		"message": "Session deleted successfully",
-	})
+	}))
 }
 
 // UpdateSessionHandler 更新Session信息
 func UpdateSessionHandler(c *gin.Context) {
-	sessionKey := c.Param("key")
+	sessionKey := resolveSessionKey(c.Param("key"))
 	if sessionKey == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Session key is required",
@@ -267,8 +501,13 @@ func UpdateSessionHandler(c *gin.Context) {
 		return
 	}
 
+	// 省略的字段保持不变
 	var req struct {
-		OrgID string `json:"orgID"`
+		OrgID         *string   `json:"orgID"`
+		Tags          *[]string `json:"tags"`
+		Tier          *string   `json:"tier"`
+		AllowedModels *[]string `json:"allowedModels"`
+		MaxConcurrent *int      `json:"maxConcurrent"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -279,24 +518,51 @@ func UpdateSessionHandler(c *gin.Context) {
 	}
 
 	config.ConfigInstance.RwMutx.Lock()
-	defer config.ConfigInstance.RwMutx.Unlock()
-
 	// 查找并更新Session
-	found := false
+	index := -1
 	for i, session := range config.ConfigInstance.Sessions {
 		if session.SessionKey == sessionKey {
-			config.ConfigInstance.Sessions[i].OrgID = req.OrgID
-			found = true
+			index = i
 			break
 		}
 	}
-
-	if !found {
+	if index < 0 {
+		config.ConfigInstance.RwMutx.Unlock()
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Session not found",
 		})
 		return
 	}
+	updated := config.ConfigInstance.Sessions[index]
+	if req.OrgID != nil {
+		updated.OrgID = *req.OrgID
+	}
+	if req.Tags != nil {
+		updated.Tags = *req.Tags
+	}
+	if req.Tier != nil {
+		updated.Tier = *req.Tier
+	}
+	if req.AllowedModels != nil {
+		updated.AllowedModels = *req.AllowedModels
+	}
+	if req.MaxConcurrent != nil {
+		updated.MaxConcurrent = *req.MaxConcurrent
+	}
+	if err := updated.Validate(); err != nil {
+		config.ConfigInstance.RwMutx.Unlock()
+		writeValidationError(c, "Invalid session", err)
+		return
+	}
+	config.ConfigInstance.Sessions[index] = updated
+	config.ConfigInstance.RwMutx.Unlock()
+
+	// 同步到SessionManager，健康状态保留
+	if config.ConfigInstance.IsSessionManagerEnabled() {
+		if sessionManager := config.ConfigInstance.GetSessionManager(); sessionManager != nil {
+			sessionManager.UpdateSession(updated)
+		}
+	}
 
 	// 广播WebSocket消息
 	if WebSocketServiceInstance != nil {
@@ -304,95 +570,370 @@ func UpdateSessionHandler(c *gin.Context) {
 		WebSocketServiceInstance.BroadcastSessions()
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	if !canReadSessionKeys(c) {
+		updated.SessionKey = logger.MaskSecret(updated.SessionKey)
+	}
+	c.JSON(http.StatusOK, persistConfig(gin.H{
 		"message": "Session updated successfully",
-	})
+		"session": updated,
+		"id":      config.SessionID(sessionKey),
+	}))
 }
 
 // ConfigHandler 获取当前配置信息
 func ConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, currentConfigInfo())
+}
+
+// currentConfigInfo 管理界面展示的配置，时间类配置以秒为单位，与更新接口一致
+func currentConfigInfo() map[string]interface{} {
+	cfg := config.ConfigInstance
+	cfg.RwMutx.RLock()
+	defer cfg.RwMutx.RUnlock()
+
 	configInfo := map[string]interface{}{
-		"sessionManagerEnabled": config.ConfigInstance.IsSessionManagerEnabled(),
-		"totalSessions":         len(config.ConfigInstance.Sessions),
-		"retryCount":           config.ConfigInstance.RetryCount,
-		"chatDelete":           config.ConfigInstance.ChatDelete,
-		"maxChatHistoryLength": config.ConfigInstance.MaxChatHistoryLength,
+		"sessionManagerEnabled":  cfg.IsSessionManagerEnabled(),
+		"totalSessions":          len(cfg.Sessions),
+		"retryCount":             cfg.RetryCount,
+		"chatDelete":             cfg.ChatDelete,
+		"maxChatHistoryLength":   cfg.MaxChatHistoryLength,
+		"noRolePrefix":           cfg.NoRolePrefix,
+		"promptDisableArtifacts": cfg.PromptDisableArtifacts,
+		"thinkingMode":           cfg.GetThinkingMode(""),
+		"enableMirrorApi":        cfg.EnableMirrorApi,
+		"mirrorApiPrefix":        cfg.MirrorApiPrefix,
+		"rateLimit": map[string]interface{}{
+			"enabled":  cfg.RateLimit.Enabled,
+			"keyBy":    cfg.RateLimit.WithDefaults().KeyBy,
+			"requests": cfg.RateLimit.Requests,
+			"tokens":   cfg.RateLimit.Tokens,
+			"window":   cfg.RateLimit.WithDefaults().Window.Seconds(),
+		},
 	}
+	if cfg.AdminAuth == "" {
+		configInfo["adminAuth"] = config.AdminAuthJWTOnly
+	} else {
+		configInfo["adminAuth"] = cfg.AdminAuth
+	}
+	configInfo["tlsEnabled"] = cfg.TLS.Enabled()
 
-	if config.ConfigInstance.IsSessionManagerEnabled() {
-		sm := config.ConfigInstance.GetSessionManager()
+	if cfg.IsSessionManagerEnabled() {
+		managerConfig := cfg.GetSessionManager().GetConfig()
+		cooldownPeriods := make(map[string]float64, len(managerConfig.CooldownPeriods))
+		for errorType, period := range managerConfig.CooldownPeriods {
+			cooldownPeriods[errorType] = period.Seconds()
+		}
 		configInfo["sessionManager"] = map[string]interface{}{
-			"scheduleStrategy":     config.ConfigInstance.SessionManager.ScheduleStrategy,
-			"maxRetryAttempts":     sm.GetMaxRetryAttempts(),
-			"healthCheckInterval":  config.ConfigInstance.SessionManager.HealthCheckInterval,
-			"minHealthScore":       config.ConfigInstance.SessionManager.MinHealthScore,
-			"circuitBreakerEnabled": config.ConfigInstance.SessionManager.CircuitBreakerEnabled,
+			"scheduleStrategy":        managerConfig.ScheduleStrategy,
+			"maxRetryAttempts":        managerConfig.MaxRetryAttempts,
+			"healthCheckInterval":     managerConfig.HealthCheckInterval.Seconds(),
+			"minHealthScore":          managerConfig.MinHealthScore,
+			"circuitBreakerEnabled":   managerConfig.CircuitBreakerEnabled,
+			"circuitBreakerThreshold": managerConfig.CircuitBreakerThreshold,
+			"circuitBreakerTimeout":   managerConfig.CircuitBreakerTimeout.Seconds(),
+			"cooldownPeriods":         cooldownPeriods,
+			"queueMaxWait":            managerConfig.QueueMaxWait.Seconds(),
+			"queueMaxSize":            managerConfig.QueueMaxSize,
+			"probeEnabled":            managerConfig.ProbeEnabled,
+			"probeConcurrency":        managerConfig.ProbeConcurrency,
+			"routing":                 managerConfig.Routing,
+			"maxConcurrent":           managerConfig.MaxConcurrent,
 		}
 	}
 
-	c.JSON(http.StatusOK, configInfo)
+	return configInfo
 }
 
-// UpdateConfigHandler 更新系统配置
-func UpdateConfigHandler(c *gin.Context) {
-	var req struct {
-		SessionManagerEnabled    bool                   `json:"sessionManagerEnabled"`
-		TotalSessions            int                    `json:"totalSessions"`
-		RetryCount              int                    `json:"retryCount"`
-		ChatDelete              bool                   `json:"chatDelete"`
-		MaxChatHistoryLength    int                    `json:"maxChatHistoryLength"`
-		NoRolePrefix            bool                   `json:"noRolePrefix"`
-		PromptDisableArtifacts  bool                   `json:"promptDisableArtifacts"`
-		EnableMirrorApi         bool                   `json:"enableMirrorApi"`
-		MirrorApiPrefix         string                 `json:"mirrorApiPrefix"`
-		SessionManager          *map[string]interface{} `json:"sessionManager"`
+// parseJSONDuration 解析秒数（数字）或 duration 字符串（如 "30s"）
+func parseJSONDuration(data json.RawMessage) (time.Duration, bool) {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if parsed, err := time.ParseDuration(text); err == nil {
+			return parsed, true
+		}
 	}
+	return 0, false
+}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// configUpdate 更新系统配置的请求，省略的字段保持不变
+type configUpdate struct {
+	SessionManagerEnabled  *bool                 `json:"sessionManagerEnabled"`
+	RetryCount             *int                  `json:"retryCount"`
+	ChatDelete             *bool                 `json:"chatDelete"`
+	MaxChatHistoryLength   *int                  `json:"maxChatHistoryLength"`
+	NoRolePrefix           *bool                 `json:"noRolePrefix"`
+	PromptDisableArtifacts *bool                 `json:"promptDisableArtifacts"`
+	ThinkingMode           *string               `json:"thinkingMode"`
+	EnableMirrorApi        *bool                 `json:"enableMirrorApi"`
+	MirrorApiPrefix        *string               `json:"mirrorApiPrefix"`
+	SessionManager         *sessionManagerUpdate `json:"sessionManager"`
+}
+
+// sessionManagerUpdate SessionManager配置的部分更新，cooldownPeriods 只覆盖给出的错误类型
+// 时间类配置接受秒数或 duration 字符串
+type sessionManagerUpdate struct {
+	ScheduleStrategy        *string                    `json:"scheduleStrategy"`
+	MaxRetryAttempts        *int                       `json:"maxRetryAttempts"`
+	HealthCheckInterval     json.RawMessage            `json:"healthCheckInterval"`
+	MinHealthScore          *float64                   `json:"minHealthScore"`
+	CircuitBreakerEnabled   *bool                      `json:"circuitBreakerEnabled"`
+	CircuitBreakerThreshold *int                       `json:"circuitBreakerThreshold"`
+	CircuitBreakerTimeout   json.RawMessage            `json:"circuitBreakerTimeout"`
+	CooldownPeriods         map[string]json.RawMessage `json:"cooldownPeriods"`
+	QueueMaxWait            json.RawMessage            `json:"queueMaxWait"`
+	QueueMaxSize            *int                       `json:"queueMaxSize"`
+	ProbeEnabled            *bool                      `json:"probeEnabled"`
+	ProbeConcurrency        *int                       `json:"probeConcurrency"`
+	Routing                 *[]config.RoutingRule      `json:"routing"`
+	MaxConcurrent           *int                       `json:"maxConcurrent"`
+}
+
+// apply 把更新写入 managerConfig，无法解析的时间记录到 errs
+func (u *sessionManagerUpdate) apply(managerConfig *config.SessionManagerConfig, errs *config.ValidationError) {
+	duration := func(field string, raw json.RawMessage, target *time.Duration) bool {
+		if raw == nil || string(raw) == "null" {
+			return false
+		}
+		parsed, ok := parseJSONDuration(raw)
+		if !ok {
+			errs.Add("sessionManager."+field, "must be a number of seconds or a duration such as 30s")
+			return false
+		}
+		*target = parsed
+		return true
 	}
 
-	// 更新配置
-	config.ConfigInstance.RwMutx.Lock()
-	defer config.ConfigInstance.RwMutx.Unlock()
-
-	config.ConfigInstance.SessionManager.Enabled = req.SessionManagerEnabled
-	config.ConfigInstance.RetryCount = req.RetryCount
-	config.ConfigInstance.ChatDelete = req.ChatDelete
-	config.ConfigInstance.MaxChatHistoryLength = req.MaxChatHistoryLength
-	config.ConfigInstance.NoRolePrefix = req.NoRolePrefix
-	config.ConfigInstance.PromptDisableArtifacts = req.PromptDisableArtifacts
-	config.ConfigInstance.EnableMirrorApi = req.EnableMirrorApi
-	config.ConfigInstance.MirrorApiPrefix = req.MirrorApiPrefix
-
-	// 更新SessionManager详细配置
-	if req.SessionManager != nil {
-		if strategy, ok := (*req.SessionManager)["scheduleStrategy"].(string); ok {
-			config.ConfigInstance.SessionManager.ScheduleStrategy = strategy
+	if u.ScheduleStrategy != nil {
+		managerConfig.ScheduleStrategy = *u.ScheduleStrategy
+	}
+	if u.MaxRetryAttempts != nil {
+		managerConfig.MaxRetryAttempts = *u.MaxRetryAttempts
+	}
+	healthCheckIntervalSet := duration("healthCheckInterval", u.HealthCheckInterval, &managerConfig.HealthCheckInterval)
+	if u.MinHealthScore != nil {
+		managerConfig.MinHealthScore = *u.MinHealthScore
+	}
+	if u.CircuitBreakerEnabled != nil {
+		managerConfig.CircuitBreakerEnabled = *u.CircuitBreakerEnabled
+	}
+	if u.CircuitBreakerThreshold != nil {
+		managerConfig.CircuitBreakerThreshold = *u.CircuitBreakerThreshold
+	}
+	circuitBreakerTimeoutSet := duration("circuitBreakerTimeout", u.CircuitBreakerTimeout, &managerConfig.CircuitBreakerTimeout)
+	if u.CooldownPeriods != nil {
+		cooldownPeriods := make(map[string]time.Duration, len(managerConfig.CooldownPeriods)+len(u.CooldownPeriods))
+		for errorType, period := range managerConfig.CooldownPeriods {
+			cooldownPeriods[errorType] = period
 		}
-		if maxRetry, ok := (*req.SessionManager)["maxRetryAttempts"].(int); ok {
-			config.ConfigInstance.SessionManager.MaxRetryAttempts = maxRetry
+		for errorType, raw := range u.CooldownPeriods {
+			period := cooldownPeriods[errorType]
+			duration("cooldownPeriods."+errorType, raw, &period)
+			cooldownPeriods[errorType] = period
 		}
-		if healthInterval, ok := (*req.SessionManager)["healthCheckInterval"].(int); ok {
-			config.ConfigInstance.SessionManager.HealthCheckInterval = time.Duration(healthInterval) * time.Second
+		managerConfig.CooldownPeriods = cooldownPeriods
+	}
+	queueMaxWaitSet := duration("queueMaxWait", u.QueueMaxWait, &managerConfig.QueueMaxWait)
+	if u.QueueMaxSize != nil {
+		managerConfig.QueueMaxSize = *u.QueueMaxSize
+	}
+	if u.ProbeEnabled != nil {
+		managerConfig.ProbeEnabled = *u.ProbeEnabled
+	}
+	if u.ProbeConcurrency != nil {
+		managerConfig.ProbeConcurrency = *u.ProbeConcurrency
+	}
+	if u.Routing != nil {
+		managerConfig.Routing = *u.Routing
+	}
+	if u.MaxConcurrent != nil {
+		managerConfig.MaxConcurrent = *u.MaxConcurrent
+	}
+
+	// 这些配置项为 0 时会被替换为默认值，显式传 0 不会生效，直接拒绝
+	rejectZero := func(field string, zero bool) {
+		if zero {
+			errs.Add("sessionManager."+field, "must be greater than 0, omit it to keep the current value")
 		}
-		if minHealth, ok := (*req.SessionManager)["minHealthScore"].(float64); ok {
-			config.ConfigInstance.SessionManager.MinHealthScore = minHealth
+	}
+	rejectZero("maxRetryAttempts", u.MaxRetryAttempts != nil && *u.MaxRetryAttempts == 0)
+	rejectZero("healthCheckInterval", healthCheckIntervalSet && managerConfig.HealthCheckInterval == 0)
+	rejectZero("minHealthScore", u.MinHealthScore != nil && *u.MinHealthScore == 0)
+	rejectZero("circuitBreakerThreshold", u.CircuitBreakerThreshold != nil && *u.CircuitBreakerThreshold == 0)
+	rejectZero("circuitBreakerTimeout", circuitBreakerTimeoutSet && managerConfig.CircuitBreakerTimeout == 0)
+	rejectZero("queueMaxWait", queueMaxWaitSet && managerConfig.QueueMaxWait == 0)
+	rejectZero("queueMaxSize", u.QueueMaxSize != nil && *u.QueueMaxSize == 0)
+	rejectZero("probeConcurrency", u.ProbeConcurrency != nil && *u.ProbeConcurrency == 0)
+}
+
+// UpdateConfigHandler 更新系统配置（PUT 与 PATCH 均为部分更新）
+// 全部字段校验通过后才应用，SessionManager的调度策略、冷却时间与熔断阈值立即生效
+func UpdateConfigHandler(c *gin.Context) {
+	var req configUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Invalid configuration",
+				"fields": []config.FieldError{{Field: typeErr.Field, Message: fmt.Sprintf("must be a %s", typeErr.Type)}},
+			})
+			return
 		}
-		if circuitBreaker, ok := (*req.SessionManager)["circuitBreakerEnabled"].(bool); ok {
-			config.ConfigInstance.SessionManager.CircuitBreakerEnabled = circuitBreaker
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	cfg := config.ConfigInstance
+	cfg.RwMutx.Lock()
+
+	// 在副本上应用更新并校验，失败时不修改当前配置
+	next := struct {
+		sessionManager         config.SessionManagerConfig
+		retryCount             int
+		chatDelete             bool
+		maxChatHistoryLength   int
+		noRolePrefix           bool
+		promptDisableArtifacts bool
+		thinkingMode           string
+		enableMirrorApi        bool
+		mirrorApiPrefix        string
+	}{
+		cfg.SessionManager, cfg.RetryCount, cfg.ChatDelete, cfg.MaxChatHistoryLength, cfg.NoRolePrefix,
+		cfg.PromptDisableArtifacts, cfg.ThinkingMode, cfg.EnableMirrorApi, cfg.MirrorApiPrefix,
+	}
+	if req.SessionManagerEnabled != nil {
+		next.sessionManager.Enabled = *req.SessionManagerEnabled
+	}
+	if req.RetryCount != nil {
+		next.retryCount = *req.RetryCount
+	}
+	if req.ChatDelete != nil {
+		next.chatDelete = *req.ChatDelete
+	}
+	if req.MaxChatHistoryLength != nil {
+		next.maxChatHistoryLength = *req.MaxChatHistoryLength
+	}
+	if req.NoRolePrefix != nil {
+		next.noRolePrefix = *req.NoRolePrefix
+	}
+	if req.PromptDisableArtifacts != nil {
+		next.promptDisableArtifacts = *req.PromptDisableArtifacts
+	}
+	if req.ThinkingMode != nil {
+		next.thinkingMode = *req.ThinkingMode
+	}
+	if req.EnableMirrorApi != nil {
+		next.enableMirrorApi = *req.EnableMirrorApi
+	}
+	if req.MirrorApiPrefix != nil {
+		next.mirrorApiPrefix = *req.MirrorApiPrefix
+	}
+	errs := &config.ValidationError{}
+	if req.SessionManager != nil {
+		req.SessionManager.apply(&next.sessionManager, errs)
+	}
+	if next.retryCount < 0 {
+		errs.Add("retryCount", "must be non-negative")
+	}
+	if next.maxChatHistoryLength < 0 {
+		errs.Add("maxChatHistoryLength", "must be non-negative")
+	}
+	if next.thinkingMode != "" && !config.IsValidThinkingMode(next.thinkingMode) {
+		errs.Add("thinkingMode", "must be one of %s, %s, %s", config.ThinkingModeInline, config.ThinkingModeReasoning, config.ThinkingModeDrop)
+	}
+	if next.mirrorApiPrefix != "" && !strings.HasPrefix(next.mirrorApiPrefix, "/") {
+		errs.Add("mirrorApiPrefix", "must start with /")
+	}
+	var managerErrs *config.ValidationError
+	if errors.As(next.sessionManager.Validate(), &managerErrs) {
+		errs.Fields = append(errs.Fields, managerErrs.Fields...)
+	}
+	if len(errs.Fields) > 0 {
+		cfg.RwMutx.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid configuration",
+			"fields": errs.Fields,
+		})
+		return
+	}
+
+	// 先让已有的SessionManager在线切换配置，失败时当前配置保持不变
+	if sm := cfg.GetSessionManager(); sm != nil {
+		if err := sm.ApplyConfig(next.sessionManager); err != nil {
+			cfg.RwMutx.Unlock()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 	}
 
-	// 如果SessionManager状态改变，重新初始化
-	if req.SessionManagerEnabled {
-		if config.ConfigInstance.GetSessionManager() == nil {
-			config.ConfigInstance.GetSessionManager()
+	cfg.SessionManager = next.sessionManager
+	cfg.RetryCount = next.retryCount
+	cfg.ChatDelete = next.chatDelete
+	cfg.MaxChatHistoryLength = next.maxChatHistoryLength
+	cfg.NoRolePrefix = next.noRolePrefix
+	cfg.PromptDisableArtifacts = next.promptDisableArtifacts
+	cfg.ThinkingMode = next.thinkingMode
+	cfg.EnableMirrorApi = next.enableMirrorApi
+	cfg.MirrorApiPrefix = next.mirrorApiPrefix
+	cfg.RwMutx.Unlock()
+
+	// 首次启用时按新配置创建SessionManager
+	cfg.GetSessionManager()
+
+	c.JSON(http.StatusOK, persistConfig(gin.H{
+		"message": "Configuration updated successfully",
+		"config":  currentConfigInfo(),
+	}))
+}
+
+// ReloadConfigHandler 重新加载配置文件并应用差异
+func ReloadConfigHandler(c *gin.Context) {
+	result, err := config.ConfigInstance.Reload()
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, config.ErrReloadUnavailable) {
+			status = http.StatusConflict
 		}
+		resp := gin.H{"error": err.Error()}
+		var validationErr *config.ValidationError
+		if errors.As(err, &validationErr) {
+			resp["fields"] = validationErr.Fields
+		}
+		c.JSON(status, resp)
+		return
+	}
+
+	if !canReadSessionKeys(c) {
+		result = maskReloadResult(result)
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Configuration reloaded successfully",
+		"result":  result,
+	})
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated successfully"})
+// writeValidationError 返回 400 及逐项的校验错误
+func writeValidationError(c *gin.Context, message string, err error) {
+	resp := gin.H{"error": message}
+	var validationErr *config.ValidationError
+	if errors.As(err, &validationErr) {
+		resp["fields"] = validationErr.Fields
+	} else {
+		resp["error"] = err.Error()
+	}
+	c.JSON(http.StatusBadRequest, resp)
+}
+
+// persistConfig 把管理界面的修改写回磁盘，失败时修改仍在内存中生效，在响应中附带警告
+func persistConfig(resp gin.H) gin.H {
+	if err := config.ConfigInstance.Persist(); err != nil {
+		logger.Error(fmt.Sprintf("Failed to persist config to %s: %v", config.ConfigInstance.PersistPath(), err))
+		resp["warning"] = "Change applied but not saved to disk: " + err.Error()
+	}
+	return resp
 }
 
 // isValidSessionKey 验证Session Key格式
@@ -445,31 +986,55 @@ func AdminLoginHandler(c *gin.Context) {
 	}
 
 	// 验证管理员凭据
-	if !middleware.ValidateAdminCredentials(req.Username, req.Password) {
+	user, err := middleware.ValidateAdminCredentials(req.Username, req.Password)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed admin login for %q from %s", req.Username, c.ClientIP()))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	// 生成JWT token
-	token, err := middleware.GenerateAdminToken(req.Username)
+	// 签发访问token与刷新token
+	pair, err := middleware.IssueAdminTokens(user)
 	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to issue admin tokens: %v", err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusOK, withAdminTokens(gin.H{
 		"message": "Login successful",
-		"token":   token,
-		"user": gin.H{
-			"username": req.Username,
-		},
-	})
+		"user":    adminUserInfo(user),
+	}, pair))
 }
 
-// AdminLogoutHandler 管理员登出
+// AdminLogoutHandler 管理员登出，吊销当前token所属的登录（token族）
+// 访问token已过期时也可以使用，或在请求体中提供 refreshToken
 func AdminLogoutHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	// 请求体可以为空
+	c.ShouldBindJSON(&req)
+
+	revoked := false
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token != "" {
+		err := middleware.RevokeAdminToken(token)
+		if err != nil && !errors.Is(err, middleware.ErrInvalidAdminToken) {
+			logger.Error(fmt.Sprintf("Failed to save admin token revocation: %v", err))
+		}
+		revoked = revoked || !errors.Is(err, middleware.ErrInvalidAdminToken)
+	}
+	if req.RefreshToken != "" {
+		err := config.ConfigInstance.GetAdminTokenStore().RevokeRefreshToken(req.RefreshToken)
+		if err != nil && !errors.Is(err, config.ErrRefreshTokenInvalid) {
+			logger.Error(fmt.Sprintf("Failed to save admin token revocation: %v", err))
+		}
+		revoked = revoked || !errors.Is(err, config.ErrRefreshTokenInvalid)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logout successful",
+		"revoked": revoked,
 	})
 }
 
@@ -481,7 +1046,14 @@ func GetAdminInfoHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"username": username,
-	})
+	user, err := config.ConfigInstance.GetAdminUserStore().Get(c.GetString("admin_user"))
+	if err != nil {
+		// 使用 API Key 访问时没有对应的账号
+		c.JSON(http.StatusOK, gin.H{
+			"username": username,
+			"role":     c.GetString("admin_role"),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, adminUserInfo(user))
 }
\ No newline at end of file
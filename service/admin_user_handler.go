@@ -0,0 +1,301 @@
+package service
+
+import (
+	"claude2api/config"
+	"claude2api/logger"
+	"claude2api/middleware"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminUserInfo 登录与 /admin/me 返回的账号信息
+func adminUserInfo(user *config.AdminUser) gin.H {
+	return gin.H{
+		"username":           user.Username,
+		"role":               user.Role,
+		"mustChangePassword": user.MustChangePassword,
+	}
+}
+
+// withAdminTokens 在登录与刷新的响应中附带访问token与刷新token
+// token 为访问token，expiresIn 为其有效秒数；刷新token只能使用一次
+func withAdminTokens(resp gin.H, pair *middleware.AdminTokenPair) gin.H {
+	resp["token"] = pair.AccessToken
+	resp["expiresIn"] = int(time.Until(pair.AccessExpiresAt).Round(time.Second).Seconds())
+	resp["refreshToken"] = pair.RefreshToken
+	resp["refreshExpiresAt"] = pair.RefreshExpiresAt
+	return resp
+}
+
+// AdminRefreshHandler 用刷新token换取新的访问token与刷新token，旧刷新token立即失效
+// 已使用过的刷新token再次出现时吊销整个登录
+func AdminRefreshHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	pair, user, err := middleware.RefreshAdminTokens(req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, config.ErrRefreshTokenReused):
+			logger.Warn(fmt.Sprintf("Reused admin refresh token from %s, login revoked", c.ClientIP()))
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Refresh token has already been used, please log in again",
+			})
+		case errors.Is(err, config.ErrRefreshTokenInvalid):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or expired refresh token",
+			})
+		default:
+			logger.Error(fmt.Sprintf("Failed to refresh admin tokens: %v", err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to refresh token",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, withAdminTokens(gin.H{
+		"message": "Token refreshed",
+		"user":    adminUserInfo(user),
+	}, pair))
+}
+
+// AdminLogoutAllHandler 退出当前账号的所有登录，包括当前登录
+func AdminLogoutAllHandler(c *gin.Context) {
+	username := c.GetString("admin_user")
+	count, err := config.ConfigInstance.GetAdminTokenStore().RevokeUser(username)
+	logger.Info(fmt.Sprintf("Admin user %q signed out of %d sessions", username, count))
+	c.JSON(http.StatusOK, withAdminUserSaveWarning(gin.H{
+		"message": "Signed out of all sessions",
+		"revoked": count,
+	}, err))
+}
+
+// ListAdminLoginsHandler 列出当前账号有效的登录
+func ListAdminLoginsHandler(c *gin.Context) {
+	logins := make([]gin.H, 0)
+	for _, family := range config.ConfigInstance.GetAdminTokenStore().ActiveFamilies(c.GetString("admin_user")) {
+		logins = append(logins, gin.H{
+			"id":           family.ID,
+			"created_at":   family.CreatedAt,
+			"refreshed_at": family.RefreshedAt,
+			"expires_at":   family.ExpiresAt,
+			"current":      family.ID == c.GetString("admin_token_family"),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": logins,
+	})
+}
+
+// revokeAdminLogins 修改密码或删除账号后吊销其全部登录，失败时记录日志
+func revokeAdminLogins(username string) {
+	if _, err := config.ConfigInstance.GetAdminTokenStore().RevokeUser(username); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save admin token revocation: %v", err))
+	}
+}
+
+// ChangeOwnPasswordHandler 当前管理员修改自己的密码，成功后此前的所有登录失效并返回新token
+// 使用初始密码的账号在修改前只能访问此接口与 /admin/me
+func ChangeOwnPasswordHandler(c *gin.Context) {
+	var req struct {
+		CurrentPassword string `json:"currentPassword" binding:"required"`
+		NewPassword     string `json:"newPassword" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	username := c.GetString("admin_user")
+	user, err := config.ConfigInstance.GetAdminUserStore().ChangePassword(username, req.CurrentPassword, req.NewPassword)
+	if user == nil {
+		switch {
+		case errors.Is(err, config.ErrInvalidAdminCredentials), errors.Is(err, config.ErrAdminUserNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Current password is incorrect",
+			})
+		default:
+			writeAdminUserError(c, "Invalid password", err)
+		}
+		return
+	}
+
+	// 其他登录随旧密码一起失效，为当前客户端签发新的登录
+	revokeAdminLogins(user.Username)
+	pair, tokenErr := middleware.IssueAdminTokens(user)
+	if tokenErr != nil {
+		logger.Error(fmt.Sprintf("Failed to issue admin tokens: %v", tokenErr))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Password changed but failed to generate a new token, please log in again",
+		})
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Admin user %q changed its password", user.Username))
+	c.JSON(http.StatusOK, withAdminUserSaveWarning(withAdminTokens(gin.H{
+		"message": "Password changed successfully",
+		"user":    adminUserInfo(user),
+	}, pair), err))
+}
+
+// ListAdminUsersHandler 列出管理员账号，不返回密码哈希
+func ListAdminUsersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"users": config.ConfigInstance.GetAdminUserStore().List(),
+	})
+}
+
+// CreateAdminUserHandler 创建管理员账号，默认要求账号在首次登录后修改初始密码
+func CreateAdminUserHandler(c *gin.Context) {
+	var req struct {
+		Username           string `json:"username"`
+		Password           string `json:"password"`
+		Role               string `json:"role"`
+		MustChangePassword *bool  `json:"mustChangePassword"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	spec := config.AdminUserSpec{
+		Username:           req.Username,
+		Password:           req.Password,
+		Role:               req.Role,
+		MustChangePassword: true,
+	}
+	if req.MustChangePassword != nil {
+		spec.MustChangePassword = *req.MustChangePassword
+	}
+	user, err := config.ConfigInstance.GetAdminUserStore().Create(spec)
+	if user == nil {
+		writeAdminUserError(c, "Invalid admin user", err)
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Admin user %q (%s) created by %s", user.Username, user.Role, c.GetString("admin_user")))
+	c.JSON(http.StatusCreated, withAdminUserSaveWarning(gin.H{
+		"message": "Admin user created successfully",
+		"user":    user,
+	}, err))
+}
+
+// UpdateAdminUserHandler 修改管理员账号的角色
+func UpdateAdminUserHandler(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	user, err := config.ConfigInstance.GetAdminUserStore().SetRole(c.Param("username"), req.Role)
+	if user == nil {
+		writeAdminUserError(c, "Invalid admin user", err)
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Admin user %q role set to %s by %s", user.Username, user.Role, c.GetString("admin_user")))
+	c.JSON(http.StatusOK, withAdminUserSaveWarning(gin.H{
+		"message": "Admin user updated successfully",
+		"user":    user,
+	}, err))
+}
+
+// ResetAdminUserPasswordHandler 为其他管理员设置新密码，账号下次登录后需修改，已签发的token立即失效
+func ResetAdminUserPasswordHandler(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	user, err := config.ConfigInstance.GetAdminUserStore().ResetPassword(c.Param("username"), req.Password)
+	if user == nil {
+		writeAdminUserError(c, "Invalid password", err)
+		return
+	}
+
+	revokeAdminLogins(user.Username)
+	logger.Info(fmt.Sprintf("Admin user %q password reset by %s", user.Username, c.GetString("admin_user")))
+	c.JSON(http.StatusOK, withAdminUserSaveWarning(gin.H{
+		"message": "Password reset successfully, the user must change it at next login",
+		"user":    user,
+	}, err))
+}
+
+// DeleteAdminUserHandler 删除管理员账号，不能删除当前登录的账号
+func DeleteAdminUserHandler(c *gin.Context) {
+	username := c.Param("username")
+	if username == c.GetString("admin_user") {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Cannot delete the current user",
+		})
+		return
+	}
+
+	err := config.ConfigInstance.GetAdminUserStore().Delete(username)
+	if errors.Is(err, config.ErrAdminUserNotFound) || errors.Is(err, config.ErrLastAdmin) {
+		writeAdminUserError(c, "", err)
+		return
+	}
+
+	revokeAdminLogins(username)
+	logger.Info(fmt.Sprintf("Admin user %q deleted by %s", username, c.GetString("admin_user")))
+	c.JSON(http.StatusOK, withAdminUserSaveWarning(gin.H{
+		"message": "Admin user deleted successfully",
+	}, err))
+}
+
+// writeAdminUserError 返回参数错误（400）、账号不存在（404）、冲突（409）或其他错误
+func writeAdminUserError(c *gin.Context, message string, err error) {
+	var validationErr *config.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeValidationError(c, message, err)
+	case errors.Is(err, config.ErrAdminUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Admin user not found",
+		})
+	case errors.Is(err, config.ErrAdminUserExists), errors.Is(err, config.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+	}
+}
+
+// withAdminUserSaveWarning 账号文件写回失败时修改仍在内存中生效，在响应中附带警告
+func withAdminUserSaveWarning(resp gin.H, err error) gin.H {
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to save admin users: %v", err))
+		resp["warning"] = "Change applied but not saved to disk: " + err.Error()
+	}
+	return resp
+}
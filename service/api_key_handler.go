@@ -0,0 +1,153 @@
+package service
+
+import (
+	"claude2api/config"
+	"claude2api/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAPIKeysHandler 列出客户端API Key及其用量，不返回明文
+func ListAPIKeysHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"apiKeys": config.ConfigInstance.GetAPIKeyRegistry().List(),
+	})
+}
+
+// GetAPIKeyHandler 获取单个客户端API Key及其用量
+func GetAPIKeyHandler(c *gin.Context) {
+	key, err := config.ConfigInstance.GetAPIKeyRegistry().Get(c.Param("id"))
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"apiKey": key,
+	})
+}
+
+// CreateAPIKeyHandler 创建客户端API Key，明文只在响应中返回一次
+// expiresAt 为 RFC 3339 时间，也可以用 expiresIn 指定秒数或 "720h" 形式的有效期
+func CreateAPIKeyHandler(c *gin.Context) {
+	var req struct {
+		Name              string          `json:"name"`
+		AllowedModels     []string        `json:"allowedModels"`
+		RequestsPerMinute int             `json:"requestsPerMinute"`
+		DailyTokenBudget  int64           `json:"dailyTokenBudget"`
+		ExpiresAt         *time.Time      `json:"expiresAt"`
+		ExpiresIn         json.RawMessage `json:"expiresIn"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	spec := config.APIKeySpec{
+		Name:              req.Name,
+		AllowedModels:     req.AllowedModels,
+		RequestsPerMinute: req.RequestsPerMinute,
+		DailyTokenBudget:  req.DailyTokenBudget,
+	}
+	if req.ExpiresAt != nil {
+		spec.ExpiresAt = *req.ExpiresAt
+	}
+	if req.ExpiresIn != nil && string(req.ExpiresIn) != "null" {
+		expiresIn, ok := parseJSONDuration(req.ExpiresIn)
+		if !ok || expiresIn <= 0 {
+			errs := &config.ValidationError{}
+			errs.Add("expiresIn", "must be a positive number of seconds or a duration such as 720h")
+			writeValidationError(c, "Invalid API key", errs)
+			return
+		}
+		spec.ExpiresAt = time.Now().Add(expiresIn)
+	}
+
+	key, secret, err := config.ConfigInstance.GetAPIKeyRegistry().Create(spec)
+	if err != nil {
+		var validationErr *config.ValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(c, "Invalid API key", err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to create API key: %v", err),
+		})
+		return
+	}
+
+	logger.Info(fmt.Sprintf("API key %s (%s) created by %s", key.ID, key.Name, c.GetString("admin_user")))
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully, store the key now as it will not be shown again",
+		"apiKey":  key,
+		"key":     secret,
+	})
+}
+
+// RevokeAPIKeyHandler 吊销客户端API Key，立即失效
+func RevokeAPIKeyHandler(c *gin.Context) {
+	key, err := config.ConfigInstance.GetAPIKeyRegistry().Revoke(c.Param("id"))
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+
+	logger.Info(fmt.Sprintf("API key %s (%s) revoked by %s", key.ID, key.Name, c.GetString("admin_user")))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked successfully",
+		"apiKey":  key,
+	})
+}
+
+// RotateAPIKeyHandler 为客户端API Key生成新的明文，旧明文立即失效，限制与用量保留
+func RotateAPIKeyHandler(c *gin.Context) {
+	key, secret, err := config.ConfigInstance.GetAPIKeyRegistry().Rotate(c.Param("id"))
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+
+	logger.Info(fmt.Sprintf("API key %s (%s) rotated by %s", key.ID, key.Name, c.GetString("admin_user")))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key rotated successfully, store the new key now as it will not be shown again",
+		"apiKey":  key,
+		"key":     secret,
+	})
+}
+
+// apiKeyUsage 按Key ID汇总客户端API Key的用量，用于统计接口
+func apiKeyUsage() map[string]gin.H {
+	usage := make(map[string]gin.H)
+	for _, key := range config.ConfigInstance.GetAPIKeyRegistry().List() {
+		usage[key.ID] = gin.H{
+			"name":   key.Name,
+			"status": key.Status(),
+			"usage":  key.Usage,
+		}
+	}
+	return usage
+}
+
+// writeAPIKeyError 返回Key不存在（404）、已吊销（409）或其他错误
+func writeAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, config.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "API key not found",
+		})
+	case errors.Is(err, config.ErrAPIKeyRevoked):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "API key has been revoked",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+	}
+}
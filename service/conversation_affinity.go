@@ -0,0 +1,160 @@
+package service
+
+import (
+	"claude2api/config"
+	"claude2api/core"
+	"claude2api/logger"
+	"claude2api/model"
+	"claude2api/utils"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conversationEntry 历史指纹对应的 claude.ai 会话
+type conversationEntry struct {
+	SessionKey        string
+	OrgID             string
+	Model             string
+	ConversationID    string
+	ParentMessageUUID string
+	ExpiresAt         time.Time
+}
+
+// conversationAffinitySweepInterval 定期清理过期记录的间隔，没有新请求时过期会话也能及时删除
+const conversationAffinitySweepInterval = time.Minute
+
+// conversationAffinityStore 按消息历史指纹记录可复用的会话
+type conversationAffinityStore struct {
+	entries   map[string]*conversationEntry
+	mu        sync.Mutex
+	sweepOnce sync.Once
+}
+
+var conversationAffinity = &conversationAffinityStore{
+	entries: make(map[string]*conversationEntry),
+}
+
+// isConversationAffinityEnabled 会话亲和只在智能Session管理器下生效
+func isConversationAffinityEnabled() bool {
+	return config.ConfigInstance.ConversationAffinity && config.ConfigInstance.IsSessionManagerEnabled()
+}
+
+// Lookup 查找历史指纹对应的会话，模型不一致视为未命中
+func (s *conversationAffinityStore) Lookup(historyKey string, model string) *conversationEntry {
+	if historyKey == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictExpiredLocked()
+
+	entry, ok := s.entries[historyKey]
+	if !ok || entry.Model != model {
+		return nil
+	}
+	copied := *entry
+	return &copied
+}
+
+// Store 记录本轮回复后的历史指纹
+func (s *conversationAffinityStore) Store(key string, entry *conversationEntry) {
+	s.sweepOnce.Do(func() {
+		go s.runSweep()
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictExpiredLocked()
+
+	entry.ExpiresAt = time.Now().Add(config.ConfigInstance.GetConversationAffinityTTL())
+	s.entries[key] = entry
+}
+
+// runSweep 定期清理过期记录，首次记录时启动
+func (s *conversationAffinityStore) runSweep() {
+	ticker := time.NewTicker(conversationAffinitySweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		s.evictExpiredLocked()
+		s.mu.Unlock()
+	}
+}
+
+// Forget 删除指向某个会话的全部记录（会话已失效时调用）
+func (s *conversationAffinityStore) Forget(conversationID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range s.entries {
+		if entry.ConversationID == conversationID {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// evictExpiredLocked 清理过期记录，会话不再被引用时按 chatDelete 删除
+func (s *conversationAffinityStore) evictExpiredLocked() {
+	now := time.Now()
+	expired := make(map[string]*conversationEntry)
+	for key, entry := range s.entries {
+		if now.After(entry.ExpiresAt) {
+			expired[entry.ConversationID] = entry
+			delete(s.entries, key)
+		}
+	}
+	if !config.ConfigInstance.ChatDelete {
+		return
+	}
+	for _, entry := range s.entries {
+		delete(expired, entry.ConversationID)
+	}
+	for conversationID, entry := range expired {
+		claudeClient := getSessionClient(entry.SessionKey)
+		claudeClient.SetOrgID(entry.OrgID)
+		go cleanupConversation(claudeClient, conversationID, 3)
+	}
+}
+
+// rememberConversation 请求成功后记录包含本轮回复的历史指纹，供下一轮复用
+// 回复取实际输出给客户端的正文，与客户端下一轮回传的历史一致
+func rememberConversation(c *gin.Context, claudeClient *core.Client, session config.SessionInfo, modelName string, conversationID string, processor *utils.ChatRequestProcessor) {
+	messageUUID := c.GetString("AssistantMessageUUID")
+	if messageUUID == "" {
+		uuid, err := claudeClient.GetLatestMessageUUID(conversationID)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to get assistant message uuid: %v", err))
+			return
+		}
+		messageUUID = uuid
+	}
+
+	reply := map[string]interface{}{
+		"role":    "assistant",
+		"content": c.GetString("ResponseText"),
+	}
+	if calls, ok := c.Get("ResponseToolCalls"); ok {
+		if toolCalls, _ := calls.([]model.ToolCall); len(toolCalls) > 0 {
+			items := make([]interface{}, 0, len(toolCalls))
+			for _, call := range toolCalls {
+				items = append(items, map[string]interface{}{
+					"function": map[string]interface{}{
+						"name":      call.Function.Name,
+						"arguments": call.Function.Arguments,
+					},
+				})
+			}
+			reply["tool_calls"] = items
+		}
+	}
+
+	conversationAffinity.Store(utils.ChainFingerprint(processor.ConversationKey, reply), &conversationEntry{
+		SessionKey:        session.SessionKey,
+		OrgID:             session.OrgID,
+		Model:             modelName,
+		ConversationID:    conversationID,
+		ParentMessageUUID: messageUUID,
+	})
+}
@@ -6,8 +6,11 @@ import (
 	"claude2api/logger"
 	"claude2api/model"
 	"claude2api/utils"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,12 +35,20 @@ func ModelsHandler(c *gin.Context) {
 		{"id": "claude-opus-4-20250514"},
 	}
 
+	// 只列出请求使用的API Key可以使用的模型
+	var identity *config.APIKeyIdentity
+	if value, ok := c.Get("APIKeyIdentity"); ok {
+		identity, _ = value.(*config.APIKeyIdentity)
+	}
 	extendedModels := make([]map[string]interface{}, 0, len(models)*2)
 	for _, m := range models {
+		id, _ := m["id"].(string)
 		// 保留原有 id
-		extendedModels = append(extendedModels, m)
+		if identity == nil || identity.AllowsModel(id) {
+			extendedModels = append(extendedModels, m)
+		}
 		// 追加 -think 版本
-		if id, ok := m["id"].(string); ok {
+		if identity == nil || identity.AllowsModel(id+"-think") {
 			extendedModels = append(extendedModels, map[string]interface{}{
 				"id": id + "-think",
 			})
@@ -68,10 +79,23 @@ func ChatCompletionsHandler(c *gin.Context) {
 
 	// Process messages into prompt and extract images
 	processor := utils.NewChatRequestProcessor()
+	processor.SetTools(req.Tools, req.ToolChoice)
 	processor.ProcessMessages(req.Messages)
+	if isConversationAffinityEnabled() {
+		processor.PrepareConversation(req.Messages)
+	}
+	if processor.HasTools() {
+		c.Set("ToolsEnabled", true)
+	}
+	c.Set("ThinkingMode", req.ThinkingMode)
+	c.Set("PromptTokens", processor.EstimatePromptTokens())
+	c.Set("IncludeUsage", req.StreamOptions != nil && req.StreamOptions.IncludeUsage)
 
 	// Get model or use default
 	model := getModelOrDefault(req.Model)
+	if !authorizeModel(c, model) {
+		return
+	}
 
 	// 检查是否启用智能Session管理器
 	if config.ConfigInstance.IsSessionManagerEnabled() {
@@ -87,37 +111,75 @@ func handleIntelligentChatRequest(c *gin.Context, model string, processor *utils
 	
 	var lastError error
 	excludeKeys := make([]string, 0)
+	routing := routingContext(c, model)
+
+	// 会话亲和：历史指纹命中时，首次尝试使用原会话所在的session
+	var conversation *conversationEntry
+	if isConversationAffinityEnabled() {
+		conversation = conversationAffinity.Lookup(processor.HistoryKey, model)
+	}
 	
 	// 智能重试循环
 	for attempt := 0; attempt < sessionManager.GetMaxRetryAttempts(); attempt++ {
-		// 智能选择最佳Session
-		sessionHealth, err := sessionManager.SelectBestSession(excludeKeys)
-		if err != nil {
-			logger.Error(fmt.Sprintf("Failed to select session: %v", err))
-			break
-		}
-		
-		// 转换为SessionInfo格式以兼容现有代码
-		session := config.SessionInfo{
-			SessionKey: sessionHealth.SessionKey,
-			OrgID:      sessionHealth.OrgID,
+		var session config.SessionInfo
+		var continued *conversationEntry
+		if attempt == 0 && conversation != nil && sessionManager.IsSessionAvailable(conversation.SessionKey) &&
+			sessionManager.AcquireSession(conversation.SessionKey) == nil {
+			continued = conversation
+			session = config.SessionInfo{
+				SessionKey: conversation.SessionKey,
+				OrgID:      conversation.OrgID,
+			}
+			logger.Info(fmt.Sprintf("Conversation affinity hit: session %s, conversation %s",
+				logger.MaskSecret(session.SessionKey), conversation.ConversationID))
+		} else {
+			// 智能选择最佳Session并占用一个并发名额，全部不可用或已满时排队等待
+			sessionHealth, err := sessionManager.WaitForSession(c.Request.Context(), routing, excludeKeys)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to select session: %v", err))
+				if errors.Is(err, config.ErrNoSessionForModel) {
+					writeChatError(c, http.StatusBadRequest, fmt.Sprintf("No session can serve model %s", model))
+					return
+				}
+				if errors.Is(err, config.ErrQueueTimeout) || errors.Is(err, config.ErrQueueFull) ||
+					(attempt == 0 && errors.Is(err, config.ErrNoAvailableSessions)) {
+					writeNoSessionError(c, sessionManager)
+					return
+				}
+				break
+			}
+
+			// 转换为SessionInfo格式以兼容现有代码
+			session = config.SessionInfo{
+				SessionKey: sessionHealth.SessionKey,
+				OrgID:      sessionHealth.OrgID,
+			}
+
+			logger.Info(fmt.Sprintf("Intelligent session selection: %s (health: %.2f, attempt: %d)",
+				logger.MaskSecret(session.SessionKey), sessionHealth.HealthScore, attempt+1))
 		}
 		
-        logger.Info(fmt.Sprintf("Intelligent session selection: %s (health: %.2f, attempt: %d)", 
-            logger.MaskSecret(session.SessionKey), sessionHealth.HealthScore, attempt+1))
-		
 		// 重置processor（如果是重试）
 		if attempt > 0 {
 			processor.Prompt.Reset()
 			processor.Prompt.WriteString(processor.RootPrompt.String())
 		}
 		
-		// 执行请求并收集详细结果
-		result := executeRequestWithMetrics(c, session, model, processor, stream)
+		// 执行请求并收集详细结果，结束后（包括 panic 时）归还session的并发名额
+		result := func() *utils.RequestResult {
+			defer sessionManager.ReleaseSession(session.SessionKey)
+			return executeRequestWithMetrics(c, session, model, processor, stream, continued)
+		}()
 		
 		if result.Success {
 			// 记录成功
 			sessionManager.RecordSuccess(session.SessionKey, result.ResponseTime)
+			// 记录流中 message_limit 事件给出的限额信息
+			if value, ok := c.Get("MessageLimit"); ok {
+				if limit, _ := value.(*utils.RateLimitError); limit != nil {
+					sessionManager.UpdateRateLimit(session.SessionKey, limit.LimitType, limit.ResetsAt)
+				}
+			}
             logger.Info(fmt.Sprintf("Request successful with session %s in %v", 
                 logger.MaskSecret(session.SessionKey), result.ResponseTime))
 			return
@@ -125,10 +187,19 @@ func handleIntelligentChatRequest(c *gin.Context, model string, processor *utils
 		
 		// 分析错误类型并更新Session状态
 		errorType := utils.ClassifyError(result.StatusCode, result.Error)
-		sessionManager.RecordError(session.SessionKey, errorType, result.Error)
+		if errorType == config.ErrorRateLimit {
+			sessionManager.RecordRateLimitError(session.SessionKey, result.Error, result.LimitType, result.ResetsAt)
+		} else {
+			sessionManager.RecordError(session.SessionKey, errorType, result.Error)
+		}
 		
         logger.Error(fmt.Sprintf("Request failed with session %s: %s (%s)", 
             logger.MaskSecret(session.SessionKey), utils.GetErrorDescription(errorType), result.Error))
+		
+		// 响应已经开始写给客户端，错误已在响应中返回，不能再换session重试
+		if isResponseCommitted(result.Error) {
+			return
+		}
 		
 		// 根据错误类型决定是否继续重试
 		if utils.ShouldStopRetry(errorType) {
@@ -151,8 +222,52 @@ func handleIntelligentChatRequest(c *gin.Context, model string, processor *utils
 	
 	// 所有重试失败后的处理
 	logger.Error(fmt.Sprintf("All intelligent retry attempts failed, last error: %v", lastError))
-	c.JSON(http.StatusInternalServerError, ErrorResponse{
-		Error: "Failed to process request after intelligent retry attempts"})
+	writeChatError(c, http.StatusInternalServerError, "Failed to process request after intelligent retry attempts")
+}
+
+// isResponseCommitted 上游失败时响应是否已经开始写给客户端
+func isResponseCommitted(err error) bool {
+	return errors.Is(err, model.ErrResponseCommitted)
+}
+
+// writeNoSessionError 没有可用session时返回503，Retry-After 取最早的冷却结束时间
+func writeNoSessionError(c *gin.Context, sessionManager *config.SessionManager) {
+	retryAfter := 1
+	if next := sessionManager.EarliestRecovery(); !next.IsZero() {
+		retryAfter = int(math.Ceil(time.Until(next).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	writeChatError(c, http.StatusServiceUnavailable, "No available sessions, please retry later")
+}
+
+// authorizeModel 检查请求使用的API Key能否使用该模型，不能时返回 403
+func authorizeModel(c *gin.Context, model string) bool {
+	if value, ok := c.Get("APIKeyIdentity"); ok {
+		if identity, _ := value.(*config.APIKeyIdentity); identity != nil && !identity.AllowsModel(model) {
+			// 被拒绝的请求不计入Key的用量
+			c.Set("APIKeyDenied", true)
+			writeChatError(c, http.StatusForbidden, fmt.Sprintf("API key is not allowed to use model %s", model))
+			return false
+		}
+	}
+	return true
+}
+
+// routingContext 根据模型、API Key 与 X-Session-Tags 头（逗号分隔）构建路由条件
+func routingContext(c *gin.Context, model string) *config.RoutingContext {
+	routing := &config.RoutingContext{
+		Model:  model,
+		APIKey: c.GetString("APIKeyID"),
+	}
+	for _, tag := range strings.Split(c.GetHeader("X-Session-Tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			routing.Tags = append(routing.Tags, tag)
+		}
+	}
+	return routing
 }
 
 // handleLegacyChatRequest 使用原始逻辑处理请求（向后兼容）
@@ -185,16 +300,16 @@ func handleLegacyChatRequest(c *gin.Context, model string, processor *utils.Chat
 	}
 
 	logger.Error("Failed for all retries")
-	c.JSON(http.StatusInternalServerError, ErrorResponse{
-		Error: "Failed to process request after multiple attempts"})
+	writeChatError(c, http.StatusInternalServerError, "Failed to process request after multiple attempts")
 }
 
 // executeRequestWithMetrics 执行请求并收集详细指标
-func executeRequestWithMetrics(c *gin.Context, session config.SessionInfo, model string, processor *utils.ChatRequestProcessor, stream bool) *utils.RequestResult {
+// conversation 非空时复用该会话，只发送新轮次
+func executeRequestWithMetrics(c *gin.Context, session config.SessionInfo, model string, processor *utils.ChatRequestProcessor, stream bool, conversation *conversationEntry) *utils.RequestResult {
 	startTime := time.Now()
 	
-	// Initialize the Claude client
-	claudeClient := core.NewClient(session.SessionKey, config.ConfigInstance.Proxy, model)
+	// Get the pooled Claude client of the session
+	claudeClient := getSessionClient(session.SessionKey)
 
 	// Get org ID if not already set
 	if session.OrgID == "" {
@@ -208,29 +323,36 @@ func executeRequestWithMetrics(c *gin.Context, session config.SessionInfo, model
 
 	claudeClient.SetOrgID(session.OrgID)
 
+	if conversation != nil {
+		return continueConversationWithMetrics(c, claudeClient, session, model, processor, stream, conversation, startTime)
+	}
+
+	message := core.NewMessageRequestBuilder().WithModel(model)
+
 	// Upload images if any
 	if len(processor.ImgDataList) > 0 {
-		err := claudeClient.UploadFile(processor.ImgDataList)
+		fileUUIDs, err := claudeClient.UploadFile(processor.ImgDataList)
 		if err != nil {
 			return utils.CreateErrorResult(500, err, time.Since(startTime))
 		}
+		message.WithFiles(fileUUIDs...)
 	}
 
 	// Handle large context if needed
 	if processor.Prompt.Len() > config.ConfigInstance.MaxChatHistoryLength {
-		claudeClient.SetBigContext(processor.Prompt.String())
+		message.WithAttachment(core.ContextAttachment(processor.Prompt.String()))
 		processor.ResetForBigContext()
 		logger.Info(fmt.Sprintf("Prompt length exceeds max limit (%d), using file context", config.ConfigInstance.MaxChatHistoryLength))
 	}
 
 	// Create conversation
-	conversationID, err := claudeClient.CreateConversation()
+	conversationID, err := claudeClient.CreateConversation(model)
 	if err != nil {
 		return utils.CreateErrorResult(500, err, time.Since(startTime))
 	}
 
 	// Send message
-	statusCode, err := claudeClient.SendMessage(conversationID, processor.Prompt.String(), stream, c)
+	statusCode, err := claudeClient.SendMessage(conversationID, message.WithPrompt(processor.Prompt.String()).Build(), stream, c)
 	responseTime := time.Since(startTime)
 	
 	if err != nil {
@@ -239,14 +361,54 @@ func executeRequestWithMetrics(c *gin.Context, session config.SessionInfo, model
 		return utils.CreateErrorResult(statusCode, err, responseTime)
 	}
 
-	// Clean up conversation if enabled
-	if config.ConfigInstance.ChatDelete {
+	// 会话亲和模式下保留会话供下一轮复用，过期后再清理
+	if isConversationAffinityEnabled() {
+		rememberConversation(c, claudeClient, session, model, conversationID, processor)
+	} else if config.ConfigInstance.ChatDelete {
+		// Clean up conversation if enabled
 		go cleanupConversation(claudeClient, conversationID, 3)
 	}
 
 	return utils.CreateSuccessResult(statusCode, responseTime)
 }
 
+// getSessionClient 从SessionManager连接池获取session的客户端，不在池中时新建
+func getSessionClient(sessionKey string) *core.Client {
+	if sessionManager := config.ConfigInstance.GetSessionManager(); sessionManager != nil {
+		if claudeClient, ok := sessionManager.GetClient(sessionKey, config.ConfigInstance.Proxy).(*core.Client); ok {
+			return claudeClient
+		}
+	}
+	return core.NewClient(sessionKey, config.ConfigInstance.Proxy)
+}
+
+// continueConversationWithMetrics 在已有会话上发送新轮次
+func continueConversationWithMetrics(c *gin.Context, claudeClient *core.Client, session config.SessionInfo, model string, processor *utils.ChatRequestProcessor, stream bool, conversation *conversationEntry, startTime time.Time) *utils.RequestResult {
+	message := core.NewMessageRequestBuilder().
+		WithModel(model).
+		WithParentMessageUUID(conversation.ParentMessageUUID).
+		WithPrompt(processor.TurnPrompt)
+	if len(processor.TurnImgDataList) > 0 {
+		fileUUIDs, err := claudeClient.UploadFile(processor.TurnImgDataList)
+		if err != nil {
+			return utils.CreateErrorResult(500, err, time.Since(startTime))
+		}
+		message.WithFiles(fileUUIDs...)
+	}
+
+	claudeClient.ApplyModelSetting(model)
+	statusCode, err := claudeClient.SendMessage(conversation.ConversationID, message.Build(), stream, c)
+	responseTime := time.Since(startTime)
+	if err != nil {
+		// 会话可能已被删除或失效，后续请求回退为整段历史发送
+		conversationAffinity.Forget(conversation.ConversationID)
+		return utils.CreateErrorResult(statusCode, err, responseTime)
+	}
+
+	rememberConversation(c, claudeClient, session, model, conversation.ConversationID, processor)
+	return utils.CreateSuccessResult(statusCode, responseTime)
+}
+
 func MirrorChatHandler(c *gin.Context) {
 	if !config.ConfigInstance.EnableMirrorApi {
 		c.JSON(http.StatusForbidden, ErrorResponse{
@@ -266,7 +428,14 @@ func MirrorChatHandler(c *gin.Context) {
 
 	// Process messages into prompt and extract images
 	processor := utils.NewChatRequestProcessor()
+	processor.SetTools(req.Tools, req.ToolChoice)
 	processor.ProcessMessages(req.Messages)
+	if processor.HasTools() {
+		c.Set("ToolsEnabled", true)
+	}
+	c.Set("ThinkingMode", req.ThinkingMode)
+	c.Set("PromptTokens", processor.EstimatePromptTokens())
+	c.Set("IncludeUsage", req.StreamOptions != nil && req.StreamOptions.IncludeUsage)
 
 	// Get model or use default
 	model := getModelOrDefault(req.Model)
@@ -308,9 +477,30 @@ func parseAndValidateRequest(c *gin.Context) (*model.ChatCompletionRequest, erro
 		return nil, fmt.Errorf("no messages provided")
 	}
 
+	if req.ThinkingMode != "" && !config.IsValidThinkingMode(req.ThinkingMode) {
+		err := fmt.Errorf("thinking_mode must be one of %s, %s, %s", config.ThinkingModeInline, config.ThinkingModeReasoning, config.ThinkingModeDrop)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Invalid request: %v", err),
+		})
+		return nil, err
+	}
+
 	return &req, nil
 }
 
+// writeChatError 按请求的响应格式返回错误
+func writeChatError(c *gin.Context, status int, message string) {
+	if c.GetString("ResponseFormat") == model.FormatAnthropic {
+		errType := "api_error"
+		if status == http.StatusServiceUnavailable {
+			errType = "overloaded_error"
+		}
+		model.ReturnAnthropicError(c, status, errType, message)
+		return
+	}
+	c.JSON(status, ErrorResponse{Error: message})
+}
+
 func getModelOrDefault(model string) string {
 	if model == "" {
 		return "claude-3-7-sonnet-20250219"
@@ -336,7 +526,7 @@ func extractSessionFromAuthHeader(c *gin.Context) (config.SessionInfo, error) {
 
 func handleChatRequest(c *gin.Context, session config.SessionInfo, model string, processor *utils.ChatRequestProcessor, stream bool) bool {
 	// Initialize the Claude client
-	claudeClient := core.NewClient(session.SessionKey, config.ConfigInstance.Proxy, model)
+	claudeClient := core.NewClient(session.SessionKey, config.ConfigInstance.Proxy)
 
 	// Get org ID if not already set
 	if session.OrgID == "" {
@@ -351,34 +541,38 @@ func handleChatRequest(c *gin.Context, session config.SessionInfo, model string,
 
 	claudeClient.SetOrgID(session.OrgID)
 
+	message := core.NewMessageRequestBuilder().WithModel(model)
+
 	// Upload images if any
 	if len(processor.ImgDataList) > 0 {
-		err := claudeClient.UploadFile(processor.ImgDataList)
+		fileUUIDs, err := claudeClient.UploadFile(processor.ImgDataList)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Failed to upload file: %v", err))
 			return false
 		}
+		message.WithFiles(fileUUIDs...)
 	}
 
 	// Handle large context if needed
 	if processor.Prompt.Len() > config.ConfigInstance.MaxChatHistoryLength {
-		claudeClient.SetBigContext(processor.Prompt.String())
+		message.WithAttachment(core.ContextAttachment(processor.Prompt.String()))
 		processor.ResetForBigContext()
 		logger.Info(fmt.Sprintf("Prompt length exceeds max limit (%d), using file context", config.ConfigInstance.MaxChatHistoryLength))
 	}
 
 	// Create conversation
-	conversationID, err := claudeClient.CreateConversation()
+	conversationID, err := claudeClient.CreateConversation(model)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to create conversation: %v", err))
 		return false
 	}
 
 	// Send message
-	if _, err := claudeClient.SendMessage(conversationID, processor.Prompt.String(), stream, c); err != nil {
+	if _, err := claudeClient.SendMessage(conversationID, message.WithPrompt(processor.Prompt.String()).Build(), stream, c); err != nil {
 		logger.Error(fmt.Sprintf("Failed to send message: %v", err))
 		go cleanupConversation(claudeClient, conversationID, 3)
-		return false
+		// 响应已经开始写给客户端，错误已在响应中返回，结束重试
+		return isResponseCommitted(err)
 	}
 
 	// Clean up conversation if enabled
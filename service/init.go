@@ -8,6 +8,22 @@ import (
 func InitServices(cfg *config.Config) {
 	// Initialize WebSocket service
 	InitializeWebSocketService(cfg)
+
+	// Load client API keys before serving requests
+	cfg.GetAPIKeyRegistry()
+
+	// Load admin accounts, creating the first admin on first run
+	cfg.GetAdminUserStore()
+	cfg.GetAdminTokenStore()
+	cfg.GetAdminAPITokenStore()
+
+	// Notify dashboard clients after config hot reload
+	config.OnReload(func(result *config.ReloadResult) {
+		if WebSocketServiceInstance != nil {
+			WebSocketServiceInstance.BroadcastConfigReload(result)
+			WebSocketServiceInstance.BroadcastSessions()
+		}
+	})
 }
 
 // InitializeWebSocketService initializes the WebSocket service
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"claude2api/config"
+	"claude2api/model"
+	"claude2api/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MessagesHandler handles the Anthropic Messages API endpoint
+func MessagesHandler(c *gin.Context) {
+	var req model.AnthropicMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		model.ReturnAnthropicError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if len(req.Messages) == 0 {
+		model.ReturnAnthropicError(c, http.StatusBadRequest, "invalid_request_error", "messages: at least one message is required")
+		return
+	}
+	if req.MaxTokens <= 0 {
+		model.ReturnAnthropicError(c, http.StatusBadRequest, "invalid_request_error", "max_tokens: must be greater than 0")
+		return
+	}
+	if req.ThinkingMode != "" && !config.IsValidThinkingMode(req.ThinkingMode) {
+		model.ReturnAnthropicError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("thinking_mode: must be one of %s, %s, %s", config.ThinkingModeInline, config.ThinkingModeReasoning, config.ThinkingModeDrop))
+		return
+	}
+
+	modelName := getModelOrDefault(req.Model)
+
+	// 告知 HandleResponse 以 Anthropic 格式输出
+	c.Set("ResponseFormat", model.FormatAnthropic)
+	c.Set("RequestModel", modelName)
+	c.Set("StopSequences", req.StopSequences)
+	c.Set("ThinkingMode", req.ThinkingMode)
+	if !authorizeModel(c, modelName) {
+		return
+	}
+
+	// Process messages into prompt and extract images
+	processor := utils.NewChatRequestProcessor()
+	processor.SetTools(req.ToOpenAITools())
+	messages := req.ToOpenAIMessages()
+	processor.ProcessMessages(messages)
+	if isConversationAffinityEnabled() {
+		processor.PrepareConversation(messages)
+	}
+	if processor.HasTools() {
+		c.Set("ToolsEnabled", true)
+	}
+	c.Set("PromptTokens", processor.EstimatePromptTokens())
+
+	if config.ConfigInstance.IsSessionManagerEnabled() {
+		handleIntelligentChatRequest(c, modelName, processor, req.Stream)
+	} else {
+		handleLegacyChatRequest(c, modelName, processor, req.Stream)
+	}
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"claude2api/config"
+	"claude2api/logger"
+	"claude2api/middleware"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 批量导入请求体的最大长度
+	maxImportSize = 1 << 20
+	// 批量导入时同时进行的验证数
+	importVerifyConcurrency = 4
+)
+
+// 批量导入中每条session的处理结果
+const (
+	importAdded     = "added"
+	importDuplicate = "duplicate" // 与本次导入中前面的条目重复
+	importExists    = "exists"    // 已在配置中
+	importInvalid   = "invalid"   // sessionKey 格式、路由属性或并发上限不正确
+	importRejected  = "rejected"  // 上游验证未通过
+)
+
+// sessionImportResult 批量导入中一条session的结果
+type sessionImportResult struct {
+	Line          int                     `json:"line"`
+	SessionKey    string                  `json:"sessionKey"` // 已脱敏
+	OrgID         string                  `json:"orgID,omitempty"`
+	Status        string                  `json:"status"`
+	Message       string                  `json:"message,omitempty"`
+	Verification  *sessionKeyVerification `json:"verification,omitempty"`
+	rateLimitTier string
+	session       config.SessionInfo
+}
+
+// ImportSessionsHandler 批量导入sessions
+// 请求体为每行一个 sessionKey[:orgID]（也接受 SESSIONS 环境变量格式）、JSON 或 CSV，
+// format 查询参数指定格式，默认自动识别；verify=true 时逐个向上游验证
+func ImportSessionsHandler(c *gin.Context) {
+	format := c.DefaultQuery("format", config.ImportFormatAuto)
+	if format == config.ImportFormatAuto && strings.Contains(c.ContentType(), "csv") {
+		format = config.ImportFormatCSV
+	}
+	verify := c.Query("verify") == "true"
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Import is larger than %d bytes", maxImportSize),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read request body",
+		})
+		return
+	}
+
+	entries, format, err := config.ParseSessionImport(data, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No sessions found in import",
+		})
+		return
+	}
+
+	// 去重并检查格式
+	config.ConfigInstance.RwMutx.RLock()
+	existing := make(map[string]bool, len(config.ConfigInstance.Sessions))
+	for _, session := range config.ConfigInstance.Sessions {
+		existing[session.SessionKey] = true
+	}
+	config.ConfigInstance.RwMutx.RUnlock()
+
+	seen := make(map[string]int, len(entries))
+	results := make([]*sessionImportResult, len(entries))
+	for i, entry := range entries {
+		result := &sessionImportResult{
+			Line:       entry.Line,
+			SessionKey: logger.MaskSecret(entry.SessionKey),
+			OrgID:      entry.OrgID,
+			session:    entry.SessionInfo,
+		}
+		results[i] = result
+		validationErr := entry.Validate()
+		switch line, duplicate := seen[entry.SessionKey]; {
+		case !isValidSessionKey(entry.SessionKey):
+			result.Status = importInvalid
+			result.Message = "session key should start with 'sk-' or 'sk-ant-'"
+		case validationErr != nil:
+			result.Status = importInvalid
+			result.Message = validationErr.Error()
+		case duplicate:
+			result.Status = importDuplicate
+			result.Message = fmt.Sprintf("same session key as line %d", line)
+		case existing[entry.SessionKey]:
+			result.Status = importExists
+			result.Message = "session already exists"
+		default:
+			seen[entry.SessionKey] = entry.Line
+		}
+	}
+
+	if verify {
+		verifyImportedSessions(results)
+	}
+
+	// 添加通过检查的sessions
+	added := make([]*sessionImportResult, 0, len(results))
+	config.ConfigInstance.RwMutx.Lock()
+	for _, session := range config.ConfigInstance.Sessions {
+		existing[session.SessionKey] = true
+	}
+	for _, result := range results {
+		if result.Status != "" {
+			continue
+		}
+		// 导入期间可能已通过其他请求添加
+		if existing[result.session.SessionKey] {
+			result.Status = importExists
+			result.Message = "session already exists"
+			continue
+		}
+		result.Status = importAdded
+		config.ConfigInstance.Sessions = append(config.ConfigInstance.Sessions, result.session)
+		added = append(added, result)
+	}
+	config.ConfigInstance.RwMutx.Unlock()
+
+	if len(added) > 0 && config.ConfigInstance.IsSessionManagerEnabled() {
+		if sessionManager := config.ConfigInstance.GetSessionManager(); sessionManager != nil {
+			for _, result := range added {
+				sessionManager.AddSession(result.session)
+				if result.Verification != nil {
+					sessionManager.UpdateOrganization(result.session.SessionKey, result.session.OrgID, result.rateLimitTier)
+				}
+			}
+		}
+	}
+
+	summary := map[string]int{
+		"total":         len(results),
+		importAdded:     0,
+		importDuplicate: 0,
+		importExists:    0,
+		importInvalid:   0,
+		importRejected:  0,
+	}
+	for _, result := range results {
+		summary[result.Status]++
+	}
+	logger.Info(fmt.Sprintf("Imported sessions (%s): %d added, %d duplicate, %d existing, %d invalid, %d rejected",
+		format, summary[importAdded], summary[importDuplicate], summary[importExists], summary[importInvalid], summary[importRejected]))
+
+	resp := gin.H{
+		"message": fmt.Sprintf("Imported %d of %d sessions", len(added), len(results)),
+		"format":  format,
+		"summary": summary,
+		"results": results,
+	}
+	if len(added) == 0 {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	// 广播WebSocket消息
+	if WebSocketServiceInstance != nil {
+		WebSocketServiceInstance.BroadcastSessions()
+	}
+	c.JSON(http.StatusOK, persistConfig(resp))
+}
+
+// verifyImportedSessions 并发验证尚未判定的sessions，通过时自动填充orgID
+func verifyImportedSessions(results []*sessionImportResult) {
+	semaphore := make(chan struct{}, importVerifyConcurrency)
+	var wg sync.WaitGroup
+	for _, result := range results {
+		if result.Status != "" {
+			continue
+		}
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(result *sessionImportResult) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			verification := verifySessionKey(result.session.SessionKey, result.session.OrgID)
+			result.Verification = verification
+			if !verification.Valid {
+				result.Status = importRejected
+				result.Message = verification.Message
+				return
+			}
+			result.session.OrgID = verification.OrgID
+			result.OrgID = verification.OrgID
+			result.rateLimitTier = verification.RateLimitTier
+		}(result)
+	}
+	wg.Wait()
+}
+
+// sessionExport 导出的一条session，health 仅在启用SessionManager时提供
+type sessionExport struct {
+	SessionKey    string                `json:"sessionKey"`
+	OrgID         string                `json:"orgID"`
+	Tags          []string              `json:"tags,omitempty"`
+	Tier          string                `json:"tier,omitempty"`
+	AllowedModels []string              `json:"allowedModels,omitempty"`
+	MaxConcurrent int                   `json:"maxConcurrent,omitempty"`
+	Health        *config.SessionHealth `json:"health,omitempty"`
+}
+
+// ExportSessionsHandler 导出sessions及其健康状态
+// 默认脱敏sessionKey，unmasked=true 时导出完整的sessionKey（需要 admin 角色或 sessions:export 权限范围）；format=csv 时导出 CSV
+func ExportSessionsHandler(c *gin.Context) {
+	unmasked := c.Query("unmasked") == "true"
+	if unmasked && !middleware.HasAdminAccess(c, config.AdminRoleAdmin, config.AdminScopeSessionsExport) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Exporting unmasked session keys requires the admin role or the sessions:export scope",
+		})
+		return
+	}
+	format := c.DefaultQuery("format", config.ImportFormatJSON)
+	if format != config.ImportFormatJSON && format != config.ImportFormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be json or csv",
+		})
+		return
+	}
+
+	config.ConfigInstance.RwMutx.RLock()
+	sessions := make([]config.SessionInfo, len(config.ConfigInstance.Sessions))
+	copy(sessions, config.ConfigInstance.Sessions)
+	config.ConfigInstance.RwMutx.RUnlock()
+
+	health := make(map[string]*config.SessionHealth)
+	if config.ConfigInstance.IsSessionManagerEnabled() {
+		if sessionManager := config.ConfigInstance.GetSessionManager(); sessionManager != nil {
+			for _, session := range sessionManager.GetSessionsHealth() {
+				health[session.SessionKey] = session
+			}
+		}
+	}
+
+	exports := make([]sessionExport, 0, len(sessions))
+	for _, session := range sessions {
+		export := sessionExport{
+			SessionKey:    session.SessionKey,
+			OrgID:         session.OrgID,
+			Tags:          session.Tags,
+			Tier:          session.Tier,
+			AllowedModels: session.AllowedModels,
+			MaxConcurrent: session.MaxConcurrent,
+			Health:        health[session.SessionKey],
+		}
+		if !unmasked {
+			export.SessionKey = logger.MaskSecret(session.SessionKey)
+			if export.Health != nil {
+				export.Health.SessionKey = export.SessionKey
+			}
+		}
+		exports = append(exports, export)
+	}
+
+	if unmasked {
+		logger.Warn(fmt.Sprintf("Exported %d unmasked session keys to %s", len(exports), c.ClientIP()))
+	}
+
+	filename := fmt.Sprintf("sessions-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == config.ImportFormatCSV {
+		writeSessionsCSV(c, exports)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"exportedAt": time.Now(),
+		"masked":     !unmasked,
+		"sessions":   exports,
+	})
+}
+
+// writeSessionsCSV 以 CSV 输出导出的sessions，表头与导入兼容
+func writeSessionsCSV(c *gin.Context, exports []sessionExport) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"sessionKey", "orgID", "status", "healthScore", "rateLimitTier", "totalRequests", "errorCount", "cooldownUntil"})
+	for _, export := range exports {
+		record := []string{export.SessionKey, export.OrgID, "", "", "", "", "", ""}
+		if h := export.Health; h != nil {
+			record[2] = h.Status.String()
+			record[3] = strconv.FormatFloat(h.HealthScore, 'f', 2, 64)
+			record[4] = h.RateLimitTier
+			record[5] = strconv.Itoa(h.TotalRequests)
+			record[6] = strconv.Itoa(h.ErrorCount)
+			if !h.CooldownUntil.IsZero() {
+				record[7] = h.CooldownUntil.Format(time.RFC3339)
+			}
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+}
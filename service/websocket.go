@@ -2,6 +2,7 @@ package service
 
 import (
     "claude2api/config"
+    "claude2api/logger"
     "log"
     "net/http"
     "sync"
@@ -111,7 +112,7 @@ func (ws *WebSocketService) BroadcastStats() {
 
 	message := WebSocketMessage{
 		Type:      "stats_update",
-		Data:      stats,
+		Data:      maskStats(stats),
 		Timestamp: time.Now(),
 	}
 
@@ -128,7 +129,7 @@ func (ws *WebSocketService) BroadcastSessions() {
 
 	message := WebSocketMessage{
 		Type:      "sessions_update",
-		Data:      sessions,
+		Data:      maskSessionsHealth(sessions),
 		Timestamp: time.Now(),
 	}
 
@@ -139,7 +140,8 @@ func (ws *WebSocketService) BroadcastSessionChange(sessionKey string, action str
 	message := WebSocketMessage{
 		Type:      "session_change",
 		Data: map[string]interface{}{
-			"session_key": sessionKey,
+			"id":          config.SessionID(sessionKey),
+			"session_key": logger.MaskSecret(sessionKey),
 			"action":      action,
 		},
 		Timestamp: time.Now(),
@@ -148,6 +150,17 @@ func (ws *WebSocketService) BroadcastSessionChange(sessionKey string, action str
 	ws.broadcast <- message
 }
 
+// BroadcastConfigReload 通知前端配置已热加载
+func (ws *WebSocketService) BroadcastConfigReload(result *config.ReloadResult) {
+	message := WebSocketMessage{
+		Type:      "config_reload",
+		Data:      maskReloadResult(result),
+		Timestamp: time.Now(),
+	}
+
+	ws.broadcast <- message
+}
+
 func (ws *WebSocketService) HandleWebSocket(c *gin.Context) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -167,7 +180,7 @@ func (ws *WebSocketService) HandleWebSocket(c *gin.Context) {
 		stats := sessionManager.GetStats()
 		initialStats := WebSocketMessage{
 			Type:      "stats_update",
-			Data:      stats,
+			Data:      maskStats(stats),
 			Timestamp: time.Now(),
 		}
 		conn.WriteJSON(initialStats)
@@ -176,7 +189,7 @@ func (ws *WebSocketService) HandleWebSocket(c *gin.Context) {
 		sessions := sessionManager.GetSessionsHealth()
 		initialSessions := WebSocketMessage{
 			Type:      "sessions_update",
-			Data:      sessions,
+			Data:      maskSessionsHealth(sessions),
 			Timestamp: time.Now(),
 		}
 		conn.WriteJSON(initialSessions)
@@ -194,6 +207,40 @@ func (ws *WebSocketService) HandleWebSocket(c *gin.Context) {
 	ws.unregister <- conn
 }
 
+// WebSocket 消息广播给所有连接，其中的sessionKey一律脱敏，通过 id 引用session
+
+// maskStats 脱敏统计副本中调用记录的sessionKey
+func maskStats(stats *config.ManagerStats) *config.ManagerStats {
+	for i := range stats.CallRecords {
+		stats.CallRecords[i].SessionKey = logger.MaskSecret(stats.CallRecords[i].SessionKey)
+	}
+	return stats
+}
+
+// maskSessionsHealth 脱敏健康状态副本中的sessionKey
+func maskSessionsHealth(sessions []*config.SessionHealth) []*config.SessionHealth {
+	for _, session := range sessions {
+		maskSessionHealth(session)
+	}
+	return sessions
+}
+
+// maskReloadResult 返回sessionKey脱敏后的热加载结果副本
+func maskReloadResult(result *config.ReloadResult) *config.ReloadResult {
+	maskKeys := func(keys []string) []string {
+		masked := make([]string, len(keys))
+		for i, key := range keys {
+			masked[i] = logger.MaskSecret(key)
+		}
+		return masked
+	}
+	resultCopy := *result
+	resultCopy.SessionsAdded = maskKeys(result.SessionsAdded)
+	resultCopy.SessionsRemoved = maskKeys(result.SessionsRemoved)
+	resultCopy.SessionsUpdated = maskKeys(result.SessionsUpdated)
+	return &resultCopy
+}
+
 // Global WebSocket service instance
 var WebSocketServiceInstance *WebSocketService
 
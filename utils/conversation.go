@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// thinkBlockRegex 匹配 inline 模式下输出的思考内容，客户端回传历史时可能保留也可能去掉
+var thinkBlockRegex = regexp.MustCompile(`(?s)^\s*<think>.*?</think>\s*`)
+
+// PrepareConversation 为会话亲和模式计算历史指纹，并生成只包含最新轮次的提示词
+// 必须在 SetTools 之后调用
+func (p *ChatRequestProcessor) PrepareConversation(messages []map[string]interface{}) {
+	lastAssistant := -1
+	fingerprint := ""
+	for i, msg := range messages {
+		fingerprint = ChainFingerprint(fingerprint, msg)
+		if role, _ := msg["role"].(string); role == "assistant" {
+			lastAssistant = i
+			p.HistoryKey = fingerprint
+		}
+	}
+	p.ConversationKey = fingerprint
+
+	turn := NewChatRequestProcessor()
+	turn.ToolsPrompt = p.ToolsPrompt
+	turn.ProcessMessages(messages[lastAssistant+1:])
+	p.TurnPrompt = turn.Prompt.String()
+	p.TurnImgDataList = turn.ImgDataList
+}
+
+// ChainFingerprint 在已有指纹后追加一条消息，得到新的历史指纹
+func ChainFingerprint(previous string, msg map[string]interface{}) string {
+	role, _ := msg["role"].(string)
+	normalized := map[string]interface{}{
+		"role":    role,
+		"content": normalizeContent(role, msg["content"]),
+	}
+	if toolCalls, ok := msg["tool_calls"].([]interface{}); ok {
+		calls := make([]string, 0, len(toolCalls))
+		for _, item := range toolCalls {
+			call, _ := item.(map[string]interface{})
+			function, _ := call["function"].(map[string]interface{})
+			name, _ := function["name"].(string)
+			arguments, _ := function["arguments"].(string)
+			calls = append(calls, name+":"+compactJSON(arguments))
+		}
+		normalized["tool_calls"] = calls
+	}
+	if id, ok := msg["tool_call_id"].(string); ok {
+		normalized["tool_call_id"] = id
+	}
+
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(append([]byte(previous), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeContent 把消息内容归一化为纯文本，图片只保留占位
+func normalizeContent(role string, content interface{}) string {
+	var sb strings.Builder
+	switch v := content.(type) {
+	case string:
+		sb.WriteString(v)
+	case []interface{}:
+		for _, item := range v {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch itemMap["type"] {
+			case "text":
+				text, _ := itemMap["text"].(string)
+				sb.WriteString(text)
+			case "image_url":
+				sb.WriteString("[image]")
+			}
+		}
+	}
+	text := sb.String()
+	if role == "assistant" {
+		text = thinkBlockRegex.ReplaceAllString(text, "")
+	}
+	return strings.TrimSpace(text)
+}
+
+// compactJSON 去除 JSON 中的空白，使参数格式差异不影响指纹
+func compactJSON(s string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"claude2api/config"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -15,10 +16,18 @@ type RequestResult struct {
 	ResponseTime time.Duration          `json:"response_time"`
 	ErrorType    config.ErrorType       `json:"error_type"`
 	Details      map[string]interface{} `json:"details,omitempty"`
+	LimitType    string                 `json:"limit_type,omitempty"`
+	ResetsAt     time.Time              `json:"resets_at,omitempty"`
 }
 
 // ClassifyError 根据状态码和错误信息分类错误类型
 func ClassifyError(statusCode int, err error) config.ErrorType {
+	// 解析出限额信息的错误（包括流中的 message_limit）
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return config.ErrorRateLimit
+	}
+
 	// 根据HTTP状态码分类
 	switch statusCode {
 	case http.StatusTooManyRequests: // 429
@@ -42,6 +51,8 @@ func ClassifyError(statusCode int, err error) config.ErrorType {
 		if strings.Contains(errMsg, "network") ||
 			strings.Contains(errMsg, "connection") ||
 			strings.Contains(errMsg, "dns") ||
+			strings.Contains(errMsg, "no such host") ||
+			strings.Contains(errMsg, "dial tcp") ||
 			strings.Contains(errMsg, "socket") {
 			return config.ErrorNetwork
 		}
@@ -139,6 +150,14 @@ func CreateErrorResult(statusCode int, err error, responseTime time.Duration) *R
 	case config.ErrorRateLimit:
 		result.Details["retry_recommended"] = true
 		result.Details["min_wait_time"] = "5m"
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			result.LimitType = rateLimitErr.LimitType
+			result.ResetsAt = rateLimitErr.ResetsAt
+			if !rateLimitErr.ResetsAt.IsZero() {
+				result.Details["resets_at"] = rateLimitErr.ResetsAt
+			}
+		}
 	case config.ErrorAuth:
 		result.Details["retry_recommended"] = false
 		result.Details["action_required"] = "check_credentials"
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitError 上游返回的限流错误，携带限额类型与重置时间
+type RateLimitError struct {
+	// LimitType 限额类型，例如 exceeded_limit
+	LimitType string
+	// ResetsAt 限额重置时间，零值表示上游未给出
+	ResetsAt time.Time
+	Message  string
+}
+
+func (e *RateLimitError) Error() string {
+	msg := "rate limit exceeded"
+	if e.LimitType != "" {
+		msg += " (" + e.LimitType + ")"
+	}
+	if !e.ResetsAt.IsZero() {
+		msg += fmt.Sprintf(", resets at %s", e.ResetsAt.Format(time.RFC3339))
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
@@ -13,6 +13,13 @@ type ChatRequestProcessor struct {
 	Prompt      strings.Builder
 	RootPrompt  strings.Builder
 	ImgDataList []string
+	ToolsPrompt string
+
+	// 会话亲和（conversationAffinity）使用的字段，由 PrepareConversation 填充
+	HistoryKey      string   // 截至最后一条 assistant 消息的历史指纹，首轮为空
+	ConversationKey string   // 全部消息的历史指纹
+	TurnPrompt      string   // 只包含最后一条 assistant 消息之后的新轮次
+	TurnImgDataList []string // 新轮次中的图片
 }
 
 // NewChatRequestProcessor creates a new processor instance
@@ -24,11 +31,22 @@ func NewChatRequestProcessor() *ChatRequestProcessor {
 	}
 }
 
+// SetTools injects tool definitions into the prompt; must be called before ProcessMessages
+func (p *ChatRequestProcessor) SetTools(tools []map[string]interface{}, toolChoice interface{}) {
+	p.ToolsPrompt = BuildToolsPrompt(tools, toolChoice)
+}
+
+// HasTools reports whether tool calling emulation is active for this request
+func (p *ChatRequestProcessor) HasTools() bool {
+	return p.ToolsPrompt != ""
+}
+
 // ProcessMessages processes the messages array into a prompt and extracts images
 func (p *ChatRequestProcessor) ProcessMessages(messages []map[string]interface{}) {
 	if config.ConfigInstance.PromptDisableArtifacts {
 		p.Prompt.WriteString("System: Forbidden to use <antArtifac> </antArtifac> to wrap code blocks, use markdown syntax instead, which means wrapping code blocks with ``` ```\n\n")
 	}
+	p.Prompt.WriteString(p.ToolsPrompt)
 
 	for _, msg := range messages {
 		role, roleOk := msg["role"].(string)
@@ -36,8 +54,15 @@ func (p *ChatRequestProcessor) ProcessMessages(messages []map[string]interface{}
 			continue // Skip invalid format
 		}
 
+		// 工具执行结果以用户消息的形式回传
+		if role == "tool" {
+			p.Prompt.WriteString(GetRolePrefix("user") + formatToolResult(msg))
+			continue
+		}
+
+		toolCalls, _ := msg["tool_calls"].([]interface{})
 		content, exists := msg["content"]
-		if !exists {
+		if !exists && len(toolCalls) == 0 {
 			continue
 		}
 
@@ -65,6 +90,9 @@ func (p *ChatRequestProcessor) ProcessMessages(messages []map[string]interface{}
 				}
 			}
 		}
+		if len(toolCalls) > 0 {
+			p.Prompt.WriteString(formatToolCalls(toolCalls) + "\n")
+		}
 	}
 	p.RootPrompt.WriteString(p.Prompt.String())
 	// Debug output
@@ -78,5 +106,6 @@ func (p *ChatRequestProcessor) ResetForBigContext() {
 	if config.ConfigInstance.PromptDisableArtifacts {
 		p.Prompt.WriteString("System: Forbidden to use <antArtifac> </antArtifac> to wrap code blocks, use markdown syntax instead, which means wrapping code blocks with ``` ```\n\n")
 	}
+	p.Prompt.WriteString(p.ToolsPrompt)
 	p.Prompt.WriteString("You must immerse yourself in the role of assistant in context.txt, cannot respond as a user, cannot reply to this message, cannot mention this message, and ignore this message in your response.\n\n")
 }
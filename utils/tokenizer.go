@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+	"unicode"
+)
+
+// EstimateTokens 估算文本的 token 数
+// 近似 Claude 的 BPE 切分：英文/数字按约 4 字符一个 token，CJK 字符各算一个，标点各算一个
+func EstimateTokens(text string) int {
+	tokens := 0
+	wordLen := 0
+	flushWord := func() {
+		if wordLen > 0 {
+			tokens += (wordLen + 3) / 4
+			wordLen = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			wordLen++
+		case unicode.IsSpace(r):
+			flushWord()
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flushWord()
+			tokens++
+		case unicode.IsLetter(r):
+			// 其他语言字母按字节长度近似
+			wordLen += 2
+		default:
+			flushWord()
+			tokens++
+		}
+	}
+	flushWord()
+	return tokens
+}
+
+// EstimateAttachmentTokens 估算 data URI 形式附件的 token 数
+// 图片按 Claude 的像素计费规则（宽×高/750，上限 1600），其他文件按解码后的大小估算
+func EstimateAttachmentTokens(dataURI string) int {
+	parts := strings.SplitN(dataURI, ",", 2)
+	if len(parts) != 2 {
+		return 0
+	}
+	data, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0
+	}
+
+	if strings.HasPrefix(parts[0], "data:image/") {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			tokens := cfg.Width * cfg.Height / 750
+			if tokens > 1600 {
+				tokens = 1600
+			}
+			return tokens
+		}
+	}
+	return len(data) / 4
+}
+
+// EstimatePromptTokens 估算本次请求的输入 token 数（提示词与附件）
+func (p *ChatRequestProcessor) EstimatePromptTokens() int {
+	tokens := EstimateTokens(p.RootPrompt.String())
+	for _, img := range p.ImgDataList {
+		tokens += EstimateAttachmentTokens(img)
+	}
+	return tokens
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 工具调用在回复中的包裹标记，core 解析时使用同一组标记
+const (
+	ToolCallOpenTag  = "<tool_call>"
+	ToolCallCloseTag = "</tool_call>"
+)
+
+// BuildToolsPrompt 将 OpenAI tools 定义与 tool_choice 转换为注入提示词的说明
+// tool_choice 为 "none" 或没有工具时返回空字符串
+func BuildToolsPrompt(tools []map[string]interface{}, toolChoice interface{}) string {
+	if len(tools) == 0 || toolChoice == "none" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("System: You have access to the following tools. Each tool is described by its name, description and JSON Schema parameters:\n\n")
+	for _, tool := range tools {
+		function, ok := tool["function"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		schema, err := json.Marshal(function)
+		if err != nil {
+			continue
+		}
+		sb.WriteString(string(schema) + "\n")
+	}
+	sb.WriteString("\nTo call a tool, reply with one block per call in exactly this format:\n")
+	sb.WriteString(ToolCallOpenTag + "\n{\"name\": \"<tool name>\", \"arguments\": {<arguments matching the schema>}}\n" + ToolCallCloseTag + "\n")
+	sb.WriteString("You may write a short explanation before the blocks, but write nothing after them. ")
+	sb.WriteString("Tool results will be returned to you in <tool_result> blocks in the next message. ")
+
+	switch v := toolChoice.(type) {
+	case string:
+		if v == "required" {
+			sb.WriteString("You must call at least one tool in this reply.")
+		} else {
+			sb.WriteString("Only call a tool when it is needed to answer; otherwise answer normally.")
+		}
+	case map[string]interface{}:
+		if function, ok := v["function"].(map[string]interface{}); ok {
+			sb.WriteString(fmt.Sprintf("You must call the tool \"%v\" in this reply.", function["name"]))
+		}
+	default:
+		sb.WriteString("Only call a tool when it is needed to answer; otherwise answer normally.")
+	}
+	sb.WriteString("\n\n")
+	return sb.String()
+}
+
+// formatToolCalls 将 assistant 历史消息中的 tool_calls 还原为提示词中的调用块
+func formatToolCalls(toolCalls []interface{}) string {
+	var sb strings.Builder
+	for _, item := range toolCalls {
+		call, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		function, ok := call["function"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// arguments 原样拼入调用块，不是合法 JSON 时重新编码，避免破坏调用块结构
+		arguments := "{}"
+		switch raw := function["arguments"].(type) {
+		case nil:
+		case string:
+			if json.Valid([]byte(raw)) {
+				arguments = raw
+			} else if raw != "" {
+				encoded, _ := json.Marshal(raw)
+				arguments = string(encoded)
+			}
+		default:
+			// 部分客户端直接传入对象
+			if encoded, err := json.Marshal(raw); err == nil {
+				arguments = string(encoded)
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%s\n{\"name\": %q, \"arguments\": %s}\n%s\n", ToolCallOpenTag, function["name"], arguments, ToolCallCloseTag))
+	}
+	return sb.String()
+}
+
+// formatToolResult 将 role 为 tool 的消息转换为 tool_result 块
+func formatToolResult(msg map[string]interface{}) string {
+	var content strings.Builder
+	switch v := msg["content"].(type) {
+	case string:
+		content.WriteString(v)
+	case []interface{}:
+		for _, item := range v {
+			if itemMap, ok := item.(map[string]interface{}); ok {
+				if text, ok := itemMap["text"].(string); ok {
+					content.WriteString(text)
+				}
+			}
+		}
+	}
+	attrs := ""
+	if id, ok := msg["tool_call_id"].(string); ok && id != "" {
+		attrs += fmt.Sprintf(" tool_call_id=%q", id)
+	}
+	if name, ok := msg["name"].(string); ok && name != "" {
+		attrs += fmt.Sprintf(" name=%q", name)
+	}
+	return fmt.Sprintf("<tool_result%s>\n%s\n</tool_result>\n\n", attrs, content.String())
+}